@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyHeader         = "X-API-Key"
+	bearerScheme         = "Bearer "
+	apiKeyScheme         = "ApiKey "
+	authenticateHeader   = `Bearer realm="db_explorer"` // `WWW-Authenticate` challenge on 401.
+	AuthRequiredErr      = "authentication required"
+	InvalidCredentialErr = "invalid credentials"
+	InvalidTokenErr      = "invalid token"
+	ExpiredTokenErr      = "token expired"
+	// ------------------ JWT ----------------------------------
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+// Authenticated client of explorer.
+type Principal struct {
+	ID     string                 `json:"id"`
+	Roles  []string               `json:"roles"`
+	Claims map[string]interface{} `json:"claims,omitempty"` // Raw token claims or static attributes.
+}
+
+// Resolve principal from request credentials. Returns (nil, nil) if request carries
+// no credentials of supported kind, so the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Require every request to be authenticated by one of authenticators.
+func WithAuthenticator(authenticators ...Authenticator) Option {
+	return func(d *DBExplorer) {
+		d.authenticators = append(d.authenticators, authenticators...)
+	}
+}
+
+type principalKey struct{}
+
+// Authenticated principal of request. Nil if authentication is not configured.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Authenticate request with configured authenticators. Request is passed as is if authentication is not configured.
+func (d *DBExplorer) authenticate(r *http.Request) (*http.Request, error) {
+	if len(d.authenticators) == 0 {
+		return r, nil
+	}
+	for _, authenticator := range d.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if principal != nil {
+			return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)), nil
+		}
+	}
+	return nil, errors.New(AuthRequiredErr)
+}
+
+// ------------------ static API keys ---------------------
+
+// Authenticate by static keys passed in `X-API-Key` header or `Authorization: ApiKey <key>`.
+type APIKeyAuthenticator struct {
+	principals map[[sha256.Size]byte]Principal // Keys are hashed to not compare secrets byte by byte.
+}
+
+func NewAPIKeyAuthenticator(keys map[string]Principal) *APIKeyAuthenticator {
+	principals := make(map[[sha256.Size]byte]Principal, len(keys))
+	for key, principal := range keys {
+		principals[sha256.Sum256([]byte(key))] = principal
+	}
+	return &APIKeyAuthenticator{principals: principals}
+}
+
+// Load API keys from json file: `{"<key>": {"id": "support", "roles": ["viewer"]}}`.
+func LoadAPIKeys(path string) (*APIKeyAuthenticator, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]Principal)
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, fmt.Errorf("invalid api keys file %s: %w", path, err)
+	}
+	return NewAPIKeyAuthenticator(keys), nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if authorization := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(authorization, apiKeyScheme) {
+		key = strings.TrimPrefix(authorization, apiKeyScheme)
+	}
+	if key == "" {
+		return nil, nil
+	}
+	principal, known := a.principals[sha256.Sum256([]byte(key))]
+	if !known {
+		return nil, errors.New(InvalidCredentialErr)
+	}
+	return &principal, nil
+}
+
+// ------------------ JWT bearer tokens ---------------------
+
+// Keys and expectations to verify JWT locally. Algorithm of token must match configured key.
+type JWTConfig struct {
+	HMACSecret   []byte         // Secret of HS256 tokens.
+	RSAPublicKey *rsa.PublicKey // Public key of RS256 tokens.
+	Issuer       string         // Expected `iss` claim. Not checked if empty.
+	Audience     string         // Expected `aud` claim. Not checked if empty.
+	Leeway       time.Duration  // Allowed clock skew for `exp` / `nbf`.
+	RolesClaim   string         // Claim with list of roles. `roles` by default.
+}
+
+// Authenticate by `Authorization: Bearer <jwt>` signed with HS256 or RS256.
+type JWTAuthenticator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(config JWTConfig) *JWTAuthenticator {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	return &JWTAuthenticator{config: config, now: time.Now}
+}
+
+// Load PEM encoded RSA public key (PKIX or PKCS#1) to verify RS256 tokens.
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not RSA public key", path)
+	}
+	return key, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, bearerScheme) {
+		return nil, nil
+	}
+	claims, err := a.verify(strings.TrimPrefix(authorization, bearerScheme))
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{Claims: claims}
+	principal.ID, _ = claims["sub"].(string)
+	if roles, ok := claims[a.config.RolesClaim].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, name)
+			}
+		}
+	}
+	return principal, nil
+}
+
+// Check signature and registered claims of token. Return all claims of valid token.
+func (a *JWTAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+	switch {
+	case header.Alg == algHS256 && len(a.config.HMACSecret) > 0:
+		mac := hmac.New(sha256.New, a.config.HMACSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New(InvalidTokenErr)
+		}
+	case header.Alg == algRS256 && a.config.RSAPublicKey != nil:
+		if rsa.VerifyPKCS1v15(a.config.RSAPublicKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New(InvalidTokenErr)
+		}
+	default: // `none` and algorithms without configured key are never accepted.
+		return nil, errors.New(InvalidTokenErr)
+	}
+
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	now := a.now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(a.config.Leeway)) {
+		return nil, errors.New(ExpiredTokenErr)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	if a.config.Issuer != "" && claims["iss"] != a.config.Issuer {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	if a.config.Audience != "" && !hasAudience(claims["aud"], a.config.Audience) {
+		return nil, errors.New(InvalidTokenErr)
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// `aud` claim is either single string or list of strings.
+func hasAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, candidate := range v {
+			if candidate == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
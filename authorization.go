@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	actionRead          = "read"
+	actionWrite         = "write"
+	actionDelete        = "delete"
+	actionAdmin         = "admin" // Maintenance of explorer itself: `_schema:admin` to reload schema.
+	wildcard            = "*"
+	permissionSeparator = ":"
+	anonymousRole       = "anonymous" // Role of requests without authenticated principal.
+	PermissionDeniedErr = "permission denied"
+	InvalidPermission   = "invalid permission %q of role %s"
+)
+
+// Roles mapped to permissions like `items:read`, `users:write`, `*:delete`, loaded from json config:
+// {"roles": {"viewer": ["*:read"], "editor": ["*:read", "*:write"], "admin": ["*:*"]}}
+// Row filters per table are applied to every principal: {"row_filters": {"items": ["owner_id = :principal.id"]}}
+type Policy struct {
+	Roles      map[string][]string `json:"roles"`
+	RowFilters map[string][]string `json:"row_filters"`
+
+	grants map[string][]Permission // Parsed permissions per role.
+}
+
+// Single grant of action on table. Both parts may be `*`.
+type Permission struct {
+	table  string
+	action string
+}
+
+func (p Permission) String() string {
+	return p.table + permissionSeparator + p.action
+}
+
+// Load and validate policy from json file.
+func LoadPolicy(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(raw, policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, policy.compile()
+}
+
+// Build policy from permissions per role.
+func NewPolicy(roles map[string][]string) (*Policy, error) {
+	policy := &Policy{Roles: roles}
+	return policy, policy.compile()
+}
+
+// Parse permissions of roles.
+func (p *Policy) compile() error {
+	p.grants = make(map[string][]Permission, len(p.Roles))
+	for role, permissions := range p.Roles {
+		for _, raw := range permissions {
+			table, action, found := strings.Cut(raw, permissionSeparator)
+			if !found || table == "" {
+				return fmt.Errorf(InvalidPermission, raw, role)
+			}
+			switch action {
+			case actionRead, actionWrite, actionDelete, actionAdmin, wildcard:
+			default:
+				return fmt.Errorf(InvalidPermission, raw, role)
+			}
+			p.grants[role] = append(p.grants[role], Permission{table: table, action: action})
+		}
+	}
+	return nil
+}
+
+// Is any role of principal granted with permission.
+func (p *Policy) allows(principal *Principal, required Permission) bool {
+	roles := []string{anonymousRole}
+	if principal != nil {
+		roles = principal.Roles
+	}
+	for _, role := range roles {
+		for _, granted := range p.grants[role] {
+			if (granted.table == wildcard || granted.table == required.table) &&
+				(granted.action == wildcard || granted.action == required.action) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Enforce role based access control on every table operation. Policy given later replaces earlier one
+// along with its row filters. Without policy every authenticated (or anonymous, if authentication is disabled)
+// request is permitted.
+func WithPolicy(policy *Policy) Option {
+	return func(d *DBExplorer) {
+		d.policy = policy
+	}
+}
+
+// Action on table performed by http method.
+func methodAction(method string) string {
+	switch method {
+	case http.MethodGet:
+		return actionRead
+	case http.MethodDelete:
+		return actionDelete
+	default:
+		return actionWrite
+	}
+}
+
+// Check principal is permitted to perform action on table.
+// Nil if permitted, otherwise denied permission is returned for error reply.
+func (d *DBExplorer) authorize(principal *Principal, table, action string) *Permission {
+	if d.policy == nil {
+		return nil
+	}
+	required := Permission{table: table, action: action}
+	if d.policy.allows(principal, required) {
+		return nil
+	}
+	return &required
+}
+
+// Tables principal is permitted to read. Used to list only accessible tables.
+func (d *DBExplorer) readableTables(principal *Principal) []string {
+	tables := d.ListTables()
+	if d.policy == nil {
+		return tables
+	}
+	readable := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if d.authorize(principal, table, actionRead) == nil {
+			readable = append(readable, table)
+		}
+	}
+	return readable
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	batchPath          = "/_batch" // Endpoint to run several operations in one transaction.
+	maxBatchOperations = 100
+	refKey             = "$ref" // Reference to result of earlier operation: {"$ref": "0.user_id"}
+	refSeparator       = "."
+	InvalidBatchErr    = "invalid batch: expected list of 1..%d operations"
+	InvalidRefErr      = "invalid reference %q"
+	UnsupportedOpErr   = "unsupported method %s"
+)
+
+// Single operation of batch: `{"method": "PUT", "table": "items", "body": {...}}`.
+// `id` is path id (`"1"`, `"1,42"`) or reference to earlier result.
+type BatchOperation struct {
+	Method string            `json:"method"`
+	Table  string            `json:"table"`
+	ID     interface{}       `json:"id,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+	Body   RequestBody       `json:"body,omitempty"`
+	// Entity tag of row, the same as `If-Match` header of standalone request.
+	IfMatch string `json:"if_match,omitempty"`
+}
+
+// Outcome of single operation in batch reply.
+type BatchResult struct {
+	Status   HTTPStatus  `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+// Execute ordered list of operations in one transaction: all of them are committed or none.
+// Reply contains result of every operation, or error of the first failed one.
+func (d *DBExplorer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		reply(w, Resp(nil, http.StatusMethodNotAllowed, errors.New(MethodNotAllowedErr)))
+		return
+	}
+	operations, err := decodeBatch(r.Body)
+	if err != nil {
+		reply(w, Resp(nil, http.StatusBadRequest, err))
+		return
+	}
+	principal := PrincipalFromContext(r.Context())
+	schema := d.schema.Load() // All operations see the same schema.
+	ctx, cancel := d.withTimeout(r.Context(), "")
+	defer cancel()
+
+	batch := &Req{ctx: ctx, schema: schema, principal: principal}
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		reply(w, d.failure(batch, http.StatusInternalServerError, err))
+		return
+	}
+	results := make([]BatchResult, 0, len(operations))
+	for i, operation := range operations {
+		result, status, err := d.executeOperation(d.bind(tx), batch, operation, results)
+		if err != nil {
+			_ = tx.Rollback()
+			response := d.failure(batch, status, err)
+			response.Details = map[string]string{"operation": strconv.Itoa(i)}
+			reply(w, response)
+			return
+		}
+		results = append(results, BatchResult{Status: status, Response: result})
+	}
+	if err := tx.Commit(); err != nil {
+		reply(w, d.failure(batch, http.StatusInternalServerError, err))
+		return
+	}
+	reply(w, Resp(map[string][]BatchResult{"results": results}, http.StatusOK, nil))
+}
+
+func decodeBatch(body io.ReadCloser) ([]BatchOperation, error) {
+	defer closeResources(body)
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber() // Numbers are casted according to column type on validation.
+	operations := make([]BatchOperation, 0, 10)
+	if err := decoder.Decode(&operations); err != nil || len(operations) == 0 || len(operations) > maxBatchOperations {
+		return nil, badRequestError(fmt.Sprintf(InvalidBatchErr, maxBatchOperations))
+	}
+	return operations, nil
+}
+
+// Run single operation within transaction applying the same restrictions as standalone request.
+// Context, schema and principal of operation are taken from `batch`.
+func (d *DBExplorer) executeOperation(tx querier, batch *Req, operation BatchOperation, results []BatchResult) (interface{}, HTTPStatus, error) {
+	method := strings.ToUpper(operation.Method)
+	tableMetadata, known := batch.schema.metadata[operation.Table]
+	if !known {
+		return nil, http.StatusNotFound, unknownTableError(UnknownTableErr)
+	}
+	if !isMethodAllowed(method, d.allowedMethods(operation.Table)) {
+		return nil, http.StatusMethodNotAllowed, errors.New(MethodNotAllowedErr)
+	}
+	if denied := d.authorize(batch.principal, operation.Table, methodAction(method)); denied != nil {
+		return nil, http.StatusForbidden, forbiddenError(fmt.Sprintf("%s: %s", PermissionDeniedErr, denied))
+	}
+
+	req := &Req{
+		ctx:       batch.ctx,
+		schema:    batch.schema,
+		table:     operation.Table,
+		params:    make(url.Values, len(operation.Params)),
+		principal: batch.principal,
+		ifMatch:   operation.IfMatch,
+	}
+	for key, value := range operation.Params {
+		req.params.Set(key, value)
+	}
+	if operation.ID != nil {
+		id, err := resolveRef(operation.ID, results)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		req.id = strings.Split(fmt.Sprint(id), idSeparator)
+	}
+	if operation.Body != nil {
+		body := make(RequestBody, len(operation.Body))
+		for key, value := range operation.Body {
+			resolved, err := resolveRef(value, results)
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			body[key] = resolved
+		}
+		req.body = filterRequestBody(body, tableMetadata.columnsInfo)
+		req.patch = &Patch{mediaType: mergePatchType, document: body} // Body of PATCH operation is merge patch.
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	if method != http.MethodGet && req.isByIdQuery() {
+		if err := d.checkPrecondition(tx, req); err != nil {
+			return nil, errorStatus(err), err
+		}
+	}
+	switch {
+	case method == http.MethodGet && req.isByIdQuery():
+		result, err = d.queryBy(tx, req)
+	case method == http.MethodGet:
+		var page *Page
+		if page, err = d.query(tx, req); err == nil {
+			result = page.content
+		}
+	case method == http.MethodPut:
+		result, err = d.put(tx, req)
+	case method == http.MethodPost:
+		result, err = d.update(tx, req)
+	case method == http.MethodPatch:
+		result, err = d.patch(tx, req)
+	case method == http.MethodDelete:
+		result, err = d.delete(tx, req)
+	default:
+		return nil, http.StatusBadRequest, badRequestError(fmt.Sprintf(UnsupportedOpErr, operation.Method))
+	}
+	if err != nil {
+		return nil, errorStatus(err), err
+	}
+	return result, http.StatusOK, nil
+}
+
+// Replace `{"$ref": "<operation>.<attribute>..."}` with value from results of earlier operations.
+// Any other value is returned as is.
+func resolveRef(value interface{}, results []BatchResult) (interface{}, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+	ref, ok := object[refKey].(string)
+	if !ok || len(object) != 1 {
+		return value, nil
+	}
+	path := strings.Split(ref, refSeparator)
+	index, err := strconv.Atoi(path[0])
+	if err != nil || index < 0 || index >= len(results) || len(path) < 2 {
+		return nil, badRequestError(fmt.Sprintf(InvalidRefErr, ref))
+	}
+	// Go through json to get the same representation as values submitted by client.
+	raw, _ := json.Marshal(results[index].Response)
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var current interface{}
+	if err := decoder.Decode(&current); err != nil {
+		return nil, badRequestError(fmt.Sprintf(InvalidRefErr, ref))
+	}
+	for _, key := range path[1:] {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, badRequestError(fmt.Sprintf(InvalidRefErr, ref))
+		}
+		if current, ok = object[key]; !ok {
+			return nil, badRequestError(fmt.Sprintf(InvalidRefErr, ref))
+		}
+	}
+	return current, nil
+}
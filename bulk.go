@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	bulkInsertQuery = "INSERT INTO `%s`(%s) VALUES %s"
+	// Upper bound of single insert statement size if server does not report its `max_allowed_packet`:
+	// default of MySQL 5.7, the lowest among supported servers.
+	defaultInsertPacket    = 4 << 20
+	maxPlaceholders        = 32766 // Limit of bound parameters in statement: SQLite has the lowest one.
+	rowOverhead            = 4     // Parentheses and separators of row values in statement.
+	autoIncrementStepQuery = "SELECT @@auto_increment_increment"
+	maxPacketQuery         = "SELECT @@max_allowed_packet"
+	NoRowsErr              = "no rows to insert"
+	InvalidRowErr          = "row %d: %s"
+	NotObjectErr           = "not an object"
+)
+
+// Insert json array of rows in one transaction. Reply with ids of all created rows in order of request.
+func (d *DBExplorer) handleBulkPut(w http.ResponseWriter, requestedData *Req) {
+	result, err := d.inTransaction(requestedData.ctx, func(tx querier) (interface{}, error) {
+		return d.put(tx, requestedData)
+	})
+	if err != nil {
+		reply(w, d.failure(requestedData, errorStatus(err), err))
+		return
+	}
+	reply(w, Resp(result, http.StatusOK, nil))
+}
+
+// Read step of generated ids, it is not 1 on multi-primary setups. Ids of rows inserted by one statement
+// can not be derived if it is unknown: rows are inserted one by one then.
+func (d *DBExplorer) initAutoIncrementStep() {
+	if d.dialect.Returning() {
+		return // Ids are returned by insert statement.
+	}
+	if err := d.conn().QueryRowContext(context.Background(), autoIncrementStepQuery).Scan(&d.autoIncrementStep); err != nil {
+		log.Printf("failed to read auto_increment_increment, bulk insert is performed row by row: %s", err)
+		d.autoIncrementStep = 0
+	}
+}
+
+// Read limit of statement size of server to fit multi-row inserts into it.
+// Databases without `max_allowed_packet` are given default limit.
+func (d *DBExplorer) initMaxInsertPacket() {
+	d.maxInsertPacket = defaultInsertPacket
+	if d.dialect.Returning() {
+		return // Not a MySQL server.
+	}
+	if err := d.conn().QueryRowContext(context.Background(), maxPacketQuery).Scan(&d.maxInsertPacket); err != nil {
+		log.Printf("failed to read max_allowed_packet, bulk insert is chunked by %d bytes: %s", defaultInsertPacket, err)
+		d.maxInsertPacket = defaultInsertPacket
+	}
+}
+
+// Validate every row with the same rules as single insert and store them with multi-row
+// `INSERT ... VALUES (...),(...)` statements, chunked to fit into packet and placeholders limits.
+func (d *DBExplorer) insertMany(q querier, req *Req) ([]interface{}, error) {
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	if len(req.rows) == 0 {
+		return nil, badRequestError(NoRowsErr)
+	}
+	columns := make([][]string, len(req.rows))
+	values := make([][]interface{}, len(req.rows))
+	for i, row := range req.rows {
+		if row == nil {
+			return nil, badRequestError(fmt.Sprintf(InvalidRowErr, i, NotObjectErr))
+		}
+		rowColumns, rowValues, err := d.insertValues(req, row)
+		if err != nil {
+			return nil, wrapRowError(i, err)
+		}
+		columns[i], values[i] = rowColumns, rowValues
+	}
+
+	// Ids of chunk are derived from id of its first row: unknown step leaves single row per chunk.
+	singleRow := tableMetadata.autoIncrementColumn() != "" && !d.dialect.Returning() && d.autoIncrementStep < 1
+	created := make([]interface{}, 0, len(req.rows))
+	for start := 0; start < len(values); {
+		// Rows of chunk share columns: rows omitting columns with defaults start chunk of their own.
+		chunkColumns := columns[start]
+		listed := "`" + strings.Join(chunkColumns, "`,`") + "`"
+		rowPlaceholders := "(" + strings.Join(strings.Split(strings.Repeat("?", len(chunkColumns)), ""), ",") + ")"
+		headerSize := len(bulkInsertQuery) + len(req.table) + len(listed)
+		end, size := start, headerSize
+		for end < len(values) {
+			rowSize := len(rowPlaceholders) + rowOverhead + valuesSize(values[end])
+			if end > start && (singleRow || len(chunkColumns) == 0 || !slices.Equal(columns[end], chunkColumns) ||
+				size+rowSize > d.maxInsertPacket || (end-start+1)*len(chunkColumns) > maxPlaceholders) {
+				break
+			}
+			size += rowSize
+			end++
+		}
+		args := make([]interface{}, 0, (end-start)*len(chunkColumns))
+		for _, rowValues := range values[start:end] {
+			args = append(args, rowValues...)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat(rowPlaceholders+",", end-start), ",")
+		sql := fmt.Sprintf(bulkInsertQuery, req.table, listed, placeholders)
+		if len(chunkColumns) == 0 {
+			sql = d.insertStatement(req.table, nil) // Row of default values only.
+		}
+		ids, err := d.insertChunk(q, req, sql, args, end-start)
+		if err != nil {
+			return nil, err
+		}
+		for i := start; i < end; i++ {
+			created = append(created, createdKey(tableMetadata, req.rows[i], ids[i-start]))
+		}
+		start = end
+	}
+	return created, nil
+}
+
+// Run multi-row insert of `count` rows. Reply with ids generated for rows, zeros if table has no auto-incremental column.
+func (d *DBExplorer) insertChunk(q querier, req *Req, sql string, args []interface{}, count int) ([]int64, error) {
+	ids := make([]int64, count)
+	autoIncrement := req.schema.metadata[req.table].autoIncrementColumn()
+	if autoIncrement != "" && d.dialect.Returning() {
+		rows, err := q.QueryContext(req.ctx, sql+fmt.Sprintf(returningClause, "`"+autoIncrement+"`"), args...)
+		if err != nil {
+			return nil, err
+		}
+		defer closeResources(rows)
+		for i := 0; rows.Next() && i < count; i++ {
+			if err := rows.Scan(&ids[i]); err != nil {
+				return nil, err
+			}
+		}
+		return ids, rows.Err()
+	}
+	result, err := q.ExecContext(req.ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if autoIncrement == "" {
+		return ids, nil
+	}
+	// Multi-row insert reports id generated for its first row, the rest follow with `auto_increment_increment` step.
+	firstID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ids {
+		ids[i] = firstID + int64(i)*d.autoIncrementStep
+	}
+	return ids, nil
+}
+
+// Approximate size of values sent to database.
+func valuesSize(values []interface{}) int {
+	size := 0
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			size += len("NULL")
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		default:
+			size += len(fmt.Sprint(v))
+		}
+	}
+	return size
+}
+
+// Failure of single row of bulk request.
+type rowError struct {
+	index int
+	err   error
+}
+
+func (e rowError) Error() string {
+	return fmt.Sprintf(InvalidRowErr, e.index, e.err)
+}
+
+func (e rowError) Unwrap() error {
+	return e.err
+}
+
+// Point client to failed row keeping status and code of original error.
+// Invalid fields are reported as `<row>.<field>`.
+func wrapRowError(index int, err error) error {
+	err = classifyError(err)
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		fields := make(map[string]string, len(invalid.fields))
+		for field, reason := range invalid.fields {
+			fields[fmt.Sprintf("%d.%s", index, field)] = reason
+		}
+		return validationError{message: fmt.Sprintf(InvalidRowErr, index, invalid), fields: fields}
+	}
+	return rowError{index: index, err: err}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Family of column types sharing the same validation and JSON representation.
+type TypeKind int
+
+const (
+	KindString   TypeKind = iota // char, varchar, text... -> string
+	KindInteger                  // tinyint, int, bigint... -> number
+	KindBool                     // tinyint(1), bool -> true / false
+	KindDecimal                  // decimal, numeric -> number without precision loss
+	KindFloat                    // float, double -> number
+	KindDate                     // date -> "2006-01-02"
+	KindDateTime                 // datetime, timestamp -> "2006-01-02 15:04:05"
+	KindTime                     // time -> "15:04:05"
+	KindYear                     // year -> number
+	KindJSON                     // json, jsonb -> embedded json value
+	KindEnum                     // enum('a','b') -> one of listed strings
+	KindSet                      // set('a','b') -> comma separated listed strings
+	KindBit                      // bit(n) -> number, bit(1) -> true / false
+	KindBinary                   // binary, varbinary, blob, bytea -> base64 string
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05.999999"
+	timeLayout     = "15:04:05.999999"
+	setSeparator   = ","
+)
+
+// Parsed column type as reported by `SHOW FULL COLUMNS`: `decimal(10,2) unsigned`, `enum('a','b')`...
+type ColumnType struct {
+	base      string   // Type name without modifiers: `decimal`, `varchar`...
+	kind      TypeKind // Family of type.
+	length    int      // Declared length / display width: `varchar(255)`, `bit(8)`. Zero if not declared.
+	precision int      // Total digits of `decimal(10,2)`.
+	scale     int      // Fraction digits of `decimal(10,2)`.
+	unsigned  bool     // Only non-negative numbers allowed.
+	values    []string // Permitted values of enum / set.
+}
+
+// Parse raw column type from database.
+func parseColumnType(raw string) ColumnType {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	colType := ColumnType{base: raw, unsigned: strings.Contains(raw, " unsigned")}
+	args := ""
+	if open := strings.IndexByte(raw, '('); open >= 0 {
+		colType.base = raw[:open]
+		if end := strings.LastIndexByte(raw, ')'); end > open {
+			args = raw[open+1 : end]
+		}
+	} else if space := strings.IndexByte(raw, ' '); space >= 0 {
+		colType.base = raw[:space]
+	}
+
+	switch colType.base {
+	case "enum", "set":
+		colType.values = parseEnumValues(args)
+	case "decimal", "numeric", "dec", "fixed", "float", "double", "real":
+		precision, scale, _ := strings.Cut(args, ",")
+		colType.precision, _ = strconv.Atoi(strings.TrimSpace(precision))
+		colType.scale, _ = strconv.Atoi(strings.TrimSpace(scale))
+	default:
+		colType.length, _ = strconv.Atoi(args)
+	}
+	colType.kind = kindOf(colType.base, colType.length)
+	return colType
+}
+
+func kindOf(base string, length int) TypeKind {
+	switch base {
+	case "tinyint":
+		if length == 1 {
+			return KindBool
+		}
+		return KindInteger
+	case "bool", "boolean":
+		return KindBool
+	case "smallint", "mediumint", "int", "integer", "bigint":
+		return KindInteger
+	case "decimal", "numeric", "dec", "fixed":
+		return KindDecimal
+	case "float", "double", "real":
+		return KindFloat
+	case "date":
+		return KindDate
+	case "datetime", "timestamp":
+		return KindDateTime
+	case "time":
+		return KindTime
+	case "year":
+		return KindYear
+	case "json", "jsonb":
+		return KindJSON
+	case "enum":
+		return KindEnum
+	case "set":
+		return KindSet
+	case "bit":
+		return KindBit
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bytea":
+		return KindBinary
+	}
+	return KindString
+}
+
+// Parse quoted list of enum / set values: `'a','b'` -> [a, b]. Doubled quote is an escaped quote.
+func parseEnumValues(args string) []string {
+	values := make([]string, 0, 4)
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(args); i++ {
+		ch := args[i]
+		switch {
+		case ch == '\'' && quoted && i+1 < len(args) && args[i+1] == '\'':
+			current.WriteByte('\'') // Escaped quote inside value.
+			i++
+		case ch == '\'':
+			quoted = !quoted
+			if !quoted {
+				values = append(values, current.String())
+				current.Reset()
+			}
+		case quoted:
+			current.WriteByte(ch)
+		}
+	}
+	return values
+}
+
+// Column stores numbers: client has to submit json numbers, filters have to be numeric.
+func (t ColumnType) isNumeric() bool {
+	switch t.kind {
+	case KindInteger, KindDecimal, KindFloat, KindYear:
+		return true
+	case KindBit:
+		return t.length != 1
+	}
+	return false
+}
+
+// Convert raw value scanned from database into value suitable for json reply.
+func (t ColumnType) decode(raw interface{}) interface{} {
+	if raw == nil {
+		return nil
+	}
+	if moment, ok := raw.(time.Time); ok {
+		return t.formatTime(moment)
+	}
+	switch t.kind {
+	case KindInteger, KindYear:
+		switch v := raw.(type) {
+		case int64, uint64:
+			return v
+		case []byte:
+			if t.unsigned {
+				parsed, _ := strconv.ParseUint(string(v), 10, 64)
+				return parsed
+			}
+			parsed, _ := strconv.ParseInt(string(v), 10, 64)
+			return parsed
+		}
+	case KindBool:
+		switch v := raw.(type) {
+		case int64:
+			return v != 0
+		case bool:
+			return v
+		case []byte:
+			return string(v) != "0" && len(v) > 0
+		}
+	case KindDecimal:
+		switch v := raw.(type) {
+		case []byte:
+			return json.Number(v)
+		case float64:
+			return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	case KindFloat:
+		switch v := raw.(type) {
+		case float64:
+			return v
+		case float32:
+			return float64(v)
+		case []byte:
+			parsed, _ := strconv.ParseFloat(string(v), 64)
+			return parsed
+		}
+	case KindJSON:
+		switch v := raw.(type) {
+		case []byte:
+			if json.Valid(v) {
+				return json.RawMessage(v)
+			}
+		case string: // SQLite stores json as text.
+			if json.Valid([]byte(v)) {
+				return json.RawMessage(v)
+			}
+		}
+	case KindBit:
+		var number uint64
+		switch v := raw.(type) {
+		case []byte:
+			padded := make([]byte, 8)
+			copy(padded[max(8-len(v), 0):], v)
+			number = binary.BigEndian.Uint64(padded)
+		case int64:
+			number = uint64(v)
+		case uint64:
+			number = v
+		}
+		if t.length == 1 {
+			return number != 0
+		}
+		return number
+	case KindBinary:
+		if v, ok := raw.([]byte); ok {
+			return v // Encoded as base64 string in json.
+		}
+	}
+	switch v := raw.(type) {
+	case []byte:
+		return string(v)
+	default:
+		return v
+	}
+}
+
+func (t ColumnType) formatTime(moment time.Time) string {
+	if t.kind == KindDate {
+		return moment.Format(dateLayout)
+	}
+	return moment.Format(dateTimeLayout)
+}
+
+// Convert value submitted by client (json decoded with numbers as json.Number) into database value.
+// Not ok if value is not acceptable for column type.
+func (t ColumnType) convert(val interface{}) (interface{}, bool) {
+	switch t.kind {
+	case KindInteger, KindYear:
+		number, ok := val.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		if t.unsigned {
+			parsed, err := strconv.ParseUint(number.String(), 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			return parsed, true
+		}
+		parsed, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return parsed, true
+	case KindDecimal:
+		var digits string
+		switch v := val.(type) {
+		case json.Number:
+			digits = v.String()
+		case string:
+			digits = v
+		default:
+			return nil, false
+		}
+		if !t.fitsDecimal(digits) || (t.unsigned && strings.HasPrefix(digits, "-")) {
+			return nil, false
+		}
+		return digits, true // Passed as string to keep precision.
+	case KindFloat:
+		number, ok := val.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		parsed, err := number.Float64()
+		if err != nil || (t.unsigned && parsed < 0) {
+			return nil, false
+		}
+		return parsed, true
+	case KindBool, KindBit:
+		switch v := val.(type) {
+		case bool:
+			if t.kind == KindBool {
+				return v, true // Driver converts to column type: 1 / 0 for MySQL, boolean for PostgreSQL.
+			}
+			if v {
+				return 1, true
+			}
+			return 0, true
+		case json.Number:
+			parsed, err := strconv.ParseUint(v.String(), 10, 64)
+			if err != nil || (t.kind == KindBool && parsed > 1) {
+				return nil, false
+			}
+			if t.kind == KindBool {
+				return parsed == 1, true
+			}
+			return parsed, true
+		}
+		return nil, false
+	case KindJSON:
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return nil, false
+		}
+		return string(encoded), true
+	case KindBinary:
+		text, ok := val.(string)
+		if !ok {
+			return nil, false
+		}
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil || (t.length > 0 && len(decoded) > t.length) {
+			return nil, false
+		}
+		return decoded, true
+	}
+
+	text, ok := val.(string)
+	if !ok {
+		return nil, false
+	}
+	switch t.kind {
+	case KindEnum:
+		if !t.permits(text) {
+			return nil, false
+		}
+	case KindSet:
+		if text != "" {
+			for _, member := range strings.Split(text, setSeparator) {
+				if !t.permits(member) {
+					return nil, false
+				}
+			}
+		}
+	case KindDate:
+		if _, err := time.Parse(dateLayout, text); err != nil {
+			return nil, false
+		}
+	case KindDateTime:
+		if _, err := time.Parse(dateTimeLayout, strings.Replace(text, "T", " ", 1)); err != nil {
+			return nil, false
+		}
+	case KindTime:
+		if _, err := time.Parse(timeLayout, text); err != nil {
+			return nil, false
+		}
+	case KindString:
+		if t.length > 0 && utf8.RuneCountInString(text) > t.length {
+			return nil, false
+		}
+	}
+	return text, true
+}
+
+// Decimal is written as `-123.45`: no exponent, no special values like `NaN`. Its digits fit declared precision,
+// fraction does not exceed scale (database would round it). Not checked against precision if it is not declared.
+func (t ColumnType) fitsDecimal(digits string) bool {
+	integer, fraction, dotted := strings.Cut(strings.TrimPrefix(digits, "-"), ".")
+	if integer == "" || (dotted && fraction == "") || !isDigits(integer) || !isDigits(fraction) {
+		return false
+	}
+	if t.precision == 0 {
+		return true
+	}
+	integer, fraction = strings.TrimLeft(integer, "0"), strings.TrimRight(fraction, "0")
+	return len(integer) <= t.precision-t.scale && len(fraction) <= t.scale
+}
+
+func isDigits(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] < '0' || text[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Is value listed among enum / set values.
+func (t ColumnType) permits(value string) bool {
+	for _, permitted := range t.values {
+		if permitted == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Value to be inserted into not nullable column without declared default missing in request. Nil if no reasonable default exists.
+func (t ColumnType) zeroValue() interface{} {
+	switch t.kind {
+	case KindInteger, KindFloat, KindBit, KindYear:
+		return 0
+	case KindBool:
+		return false
+	case KindDecimal:
+		return "0"
+	case KindString, KindSet:
+		return ""
+	case KindEnum:
+		if len(t.values) > 0 {
+			return t.values[0]
+		}
+	case KindBinary:
+		return []byte{}
+	}
+	return nil
+}
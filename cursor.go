@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	cursorParam         = "cursor"      // Query parameter to continue keyset pagination. Empty value starts from the beginning.
+	nextCursorKey       = "next_cursor" // Response attribute with cursor of the next page.
+	InvalidCursorErr    = "invalid cursor"
+	CursorOrderErr      = "cursor does not match order"
+	CursorWithOffsetErr = "cursor can not be combined with offset"
+)
+
+// Position of last returned record. Opaque for client: base64 encoded json.
+type Cursor struct {
+	Order  string        `json:"o"` // Sort specification cursor was issued for.
+	Values []interface{} `json:"v"` // Values of sort columns of last record.
+}
+
+// Canonical representation of sort columns to bind cursor to ordering: `-updated,id`.
+func orderSpec(orders []OrderBy) string {
+	tokens := make([]string, len(orders))
+	for i, order := range orders {
+		tokens[i] = order.column
+		if order.descending {
+			tokens[i] = descendingPrefix + order.column
+		}
+	}
+	return strings.Join(tokens, orderSeparator)
+}
+
+// Build cursor pointing after given entry.
+func newCursor(orders []OrderBy, entry DBEntry) string {
+	cursor := Cursor{Order: orderSpec(orders), Values: make([]interface{}, len(orders))}
+	for i, order := range orders {
+		cursor.Values[i] = entry[order.column]
+	}
+	raw, _ := json.Marshal(cursor) // Only scalar values from database - can not fail.
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode cursor submitted by client and check it was issued for the same ordering.
+// Values are encoded as client sees them: they are converted by column types the same way as written values.
+func parseCursor(raw string, orders []OrderBy, tableMetadata TableMetadata) (*Cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, badRequestError(InvalidCursorErr)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(decoded))
+	decoder.UseNumber() // Keep big integers intact.
+	cursor := &Cursor{}
+	if err := decoder.Decode(cursor); err != nil {
+		return nil, badRequestError(InvalidCursorErr)
+	}
+	if cursor.Order != orderSpec(orders) {
+		return nil, badRequestError(CursorOrderErr)
+	}
+	if len(cursor.Values) != len(orders) {
+		return nil, badRequestError(InvalidCursorErr)
+	}
+	for i, value := range cursor.Values {
+		if value == nil {
+			continue
+		}
+		converted, ok := tableMetadata.getColumn(orders[i].column).columnType.convert(value)
+		if !ok {
+			return nil, badRequestError(InvalidCursorErr)
+		}
+		cursor.Values[i] = converted
+	}
+	return cursor, nil
+}
+
+// Build keyset condition selecting records strictly after cursor position:
+// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ...
+// NULLs are treated the MySQL way: lowest values, first on ascending order and last on descending.
+func (c *Cursor) predicate(orders []OrderBy) Predicate {
+	alternatives := make([]string, 0, len(orders))
+	args := make([]interface{}, 0, len(orders)*len(orders))
+	for i, order := range orders {
+		after, afterArgs, possible := afterValue(order, c.Values[i])
+		if !possible {
+			continue
+		}
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			column := "`" + orders[j].column + "`"
+			if c.Values[j] == nil {
+				conditions = append(conditions, column+" IS NULL")
+				continue
+			}
+			conditions = append(conditions, column+" = ?")
+			args = append(args, c.Values[j])
+		}
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	if len(alternatives) == 0 {
+		return Predicate{sql: "1 = 0"} // Cursor points to the very last possible position.
+	}
+	return Predicate{sql: "(" + strings.Join(alternatives, " OR ") + ")", args: args}
+}
+
+// Condition for column value to follow `value` in given order. Not possible for NULL on descending order.
+func afterValue(order OrderBy, value interface{}) (string, []interface{}, bool) {
+	column := "`" + order.column + "`"
+	switch {
+	case value == nil && order.descending:
+		return "", nil, false
+	case value == nil:
+		return column + " IS NOT NULL", nil, true
+	case order.descending:
+		return fmt.Sprintf("(%s < ? OR %s IS NULL)", column, column), []interface{}{value}, true
+	default:
+		return column + " > ?", []interface{}{value}, true
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -8,8 +10,13 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,20 +25,28 @@ import (
 
 const (
 	// ------------------ DB QUERIES ------------------------------
-	showTablesQuery = "SHOW TABLES;"
-	selectByIdQuery = "select %s from %s WHERE %s = ?"
-	selectQuery     = "select %s from %s LIMIT ? OFFSET ?"
-	insertQuery     = "INSERT INTO %s(%s) VALUES(%s)"
-	updateQuery     = "UPDATE  `%s` SET %s WHERE `%s` = ?"
-	deleteQuery     = "DELETE FROM `%s` WHERE `%s` = ?"
+	selectByIdQuery     = "select %s from `%s`%s"
+	selectQuery         = "select %s from `%s`%s%s LIMIT ? OFFSET ?"
+	insertQuery         = "INSERT INTO `%s`(%s) VALUES(%s)"
+	insertDefaultsQuery = "INSERT INTO `%s`%s"
+	returningClause     = " RETURNING %s"
+	updateQuery         = "UPDATE  `%s` SET %s%s"
+	deleteQuery         = "DELETE FROM `%s`%s"
 	// ------------------ errors ----------------------------------
-	UnknownTableErr         = "unknown table"              // If requested table does not exist in database.
-	RecordNotFoungErr       = "record not found"           // No entries found in table by criteria.
-	InvalidIDTypeErrParrern = "field %s have invalid type" // Cleint submited invalid field for persitence in database.
+	UnknownTableErr         = "unknown table"               // If requested table does not exist in database.
+	RecordNotFoungErr       = "record not found"            // No entries found in table by criteria.
+	InvalidIDTypeErrParrern = "field %s have invalid type"  // Cleint submited invalid field for persitence in database.
+	InvalidIDErr            = "invalid id"                  // Row id in path does not match primary key of table.
+	NoPrimaryKeyErr         = "table %s has no primary key" // Rows of table can not be addressed by id.
+	MethodNotAllowedErr     = "method not allowed"          // Method is disabled by read-only mode or table config.
+	NothingToUpdateErr      = "no fields to update"         // Update request without known columns.
+	InvalidBodyErr          = "request body must be a json object"
+	NoEndpointErr           = "no such endpoint"
 	BadRequest              = "BAD_REQUEST"
 	//-------------------------------------------------------------
 	defaultLimit  = 5
 	defaultOffest = 0
+	idSeparator   = "," // Separator of composite primary key components: `/$table/1,42`
 )
 
 type (
@@ -41,40 +56,91 @@ type (
 	RequestBody = map[string]interface{}
 )
 
+// Database handle operations are performed with: connection pool or transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type DBExplorer struct {
-	db         *sql.DB                  // database handler
-	TableNames TablesList               // Keep table names after instantiating.
-	metadata   map[string]TableMetadata // Keep metadate per table after instantiating.
+	db      *sql.DB                // database handler
+	dialect Dialect                // SQL flavour of database.
+	pool    PoolConfig             // Limits of connection pools of database and replicas.
+	schema  atomic.Pointer[Schema] // Tables and their metadata, replaced as a whole on reload.
+
+	autoIncrementStep int64 // Step between ids of multi-row insert reported by LastInsertId. Unknown if zero.
+	maxInsertPacket   int   // Upper bound of size of multi-row insert statement: `max_allowed_packet` of server.
+
+	reloadMu       sync.Mutex    // Serializes schema reloads.
+	schemaVersion  string        // Checksum of catalog columns schema was loaded from.
+	schemaInterval time.Duration // Period of polling catalog for changes. No polling if zero.
+	stopPolling    chan struct{} // Closed to stop background polling of schema and replicas.
+	closeOnce      sync.Once
+
+	replicas       []*replica    // Databases serving reads of GET requests.
+	nextReplica    atomic.Uint64 // Counter of round-robin choice of replica.
+	healthInterval time.Duration // Period of pinging replicas.
+	pinWindow      time.Duration // How long client reads from primary after its write. Not pinned if zero.
+	pinKey         []byte        // Signs pin deadlines issued to clients.
+
+	queryTimeout  time.Duration            // Deadline of database operations of single request. No deadline if zero.
+	tableTimeouts map[string]time.Duration // Deadlines overriding `queryTimeout` per table.
+
+	defaultLimit int       // Page size of listing without `limit` param.
+	maxLimit     int       // Upper bound of `limit` param. Not bounded if zero.
+	requestLog   io.Writer // Destination of request tracking lines.
+	basePath     string    // Prefix explorer is mounted under in links: `/db/$name`. Empty if served from root.
+
+	readOnly     bool                // Only GET requests are permitted.
+	tableMethods map[string][]string // Http methods permitted per table. All methods if table is not listed.
+
+	exposedTables map[string]bool            // Allow list of tables. All tables are exposed if nil.
+	hiddenTables  map[string]bool            // Deny list of tables.
+	hiddenColumns map[string]map[string]bool // Columns per table invisible for reads and writes.
+	maskedColumns map[string]map[string]Mask // Columns per table with values replaced in replies.
+
+	authenticators []Authenticator     // Tried in order until one recognizes credentials. No authentication if empty.
+	policy         *Policy             // Role based permissions per table. Everything is permitted if nil.
+	rowFilterExprs map[string][]string // Row level security expressions per table configured apart from policy.
+
+	requireIfMatch bool   // Writes of rows by id without `If-Match` are rejected with 428.
+	signingKey     []byte // Secret signatures issued to clients are derived from. Random if not configured.
+	etagKey        []byte // Keys entity tags: values of hidden and masked columns can not be guessed from them.
+
+	idempotency      IdempotencyStore // Responses of writes with `Idempotency-Key` to be replayed on retries.
+	idempotencyTable string           // Table to keep idempotency records in. In-memory store is used if empty.
+	idempotencyTTL   time.Duration    // How long idempotency records are kept.
 }
 
 func Resp(content interface{}, status int, e error) Response {
-	errContent := ""
-	if e != nil {
-		errContent = e.Error()
+	if e == nil {
+		return Response{status: status, Resp: content}
 	}
-	return Response{Err: errContent, status: status, Resp: content}
+	e = classifyError(e)
+	return Response{Err: e.Error(), Code: errorCode(e, status), Fields: errorFields(e), status: status, Resp: content}
 }
 
-// Collect table metadata: column names and column types.
-func (d *DBExplorer) getColumnMetadata(tableName string) error {
-	query := fmt.Sprintf("SHOW FULL COLUMNS FROM %s", tableName)
-	rows, err := d.db.Query(query)
-	defer closeResources(rows)
-
+// Collect table metadata into schema: column names and column types.
+func (d *DBExplorer) getColumnMetadata(ctx context.Context, schema *Schema, tableName string) error {
+	columnsInfo, err := d.dialect.Columns(ctx, d.conn(), tableName)
 	if err != nil {
 		return err
 	}
-	columnsInfo := []ColumnMetadata{}
-	for rows.Next() {
-		var (
-			field, tType, tNull, key, tExtra, privileges, comment string
-			collation, tDefault                                   interface{}
-		)
-		err := rows.Scan(&field, &tType, &collation, &tNull, &key, &tDefault, &tExtra, &privileges, &comment)
+	expressions := slices.Clone(d.rowFilterExprs[tableName])
+	if d.policy != nil {
+		expressions = append(expressions, d.policy.RowFilters[tableName]...)
+	}
+	for _, expression := range expressions {
+		filter, err := parseRowFilter(tableName, expression, columnsInfo)
 		if err != nil {
 			return err
 		}
-		columnsInfo = append(columnsInfo, newColumnInfo(field, tType, tExtra, tNull))
+		schema.rowFilters[tableName] = append(schema.rowFilters[tableName], filter)
+	}
+	columnsInfo, err = d.applyColumnRules(tableName, columnsInfo)
+	if err != nil {
+		return err
 	}
 	columnNames := make([]string, len(columnsInfo))
 	for i := 0; i < len(columnsInfo); i++ {
@@ -84,63 +150,102 @@ func (d *DBExplorer) getColumnMetadata(tableName string) error {
 	for i := 0; i < len(columnsInfo); i++ {
 		hash[columnsInfo[i].fieldName] = columnsInfo[i]
 	}
-	tableMetadata := TableMetadata{columnsInfo: columnsInfo, columnNames: columnNames, hash: hash}
-	d.metadata[tableName] = tableMetadata
+	tableMetadata := TableMetadata{
+		columnsInfo: columnsInfo,
+		columnNames: columnNames,
+		hash:        hash,
+		primaryKey:  findPrimaryKey(columnsInfo),
+	}
+	schema.metadata[tableName] = tableMetadata
 	return nil
 }
 
 // Create new DB Explorer instance to handle DB-queries and http-requests
-func NewDbExplorer(db *sql.DB) (*DBExplorer, error) {
-	// Adjust default settings of DB-connection
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(2)
-	db.SetConnMaxIdleTime(1 * time.Second)
+func NewDbExplorer(db *sql.DB, options ...Option) (*DBExplorer, error) {
+	dbExplorer := &DBExplorer{
+		db:             db,
+		defaultLimit:   defaultLimit,
+		requestLog:     os.Stdout,
+		stopPolling:    make(chan struct{}),
+		healthInterval: defaultHealthInterval,
+		queryTimeout:   defaultQueryTimeout,
+		tableTimeouts:  make(map[string]time.Duration),
+		tableMethods:   make(map[string][]string),
+		hiddenTables:   make(map[string]bool),
+		hiddenColumns:  make(map[string]map[string]bool),
+		maskedColumns:  make(map[string]map[string]Mask),
+		rowFilterExprs: make(map[string][]string),
+		idempotencyTTL: defaultIdempotencyTTL,
+	}
+	for _, option := range options {
+		option(dbExplorer)
+	}
+	// Adjust settings of DB-connection
+	dbExplorer.pool.apply(db)
 	// Just verify db connection once again. `who knows....`
 	if connErr := db.Ping(); connErr != nil {
 		return nil, connErr
 	}
-
-	dbExplorer := &DBExplorer{db: db, metadata: make(map[string]TableMetadata, 10)}
-	return dbExplorer.collectMetaInfo()
+	if dbExplorer.dialect == nil {
+		dbExplorer.dialect = detectDialect(db)
+	}
+	if err := dbExplorer.initSigningKey(); err != nil {
+		return nil, err
+	}
+	dbExplorer.initPinKey()
+	dbExplorer.initAutoIncrementStep()
+	dbExplorer.initMaxInsertPacket()
+	if err := dbExplorer.initIdempotencyStore(); err != nil {
+		return nil, err
+	}
+	if err := dbExplorer.ReloadSchema(context.Background()); err != nil {
+		return nil, err
+	}
+	if dbExplorer.schemaInterval > 0 {
+		go dbExplorer.pollSchema()
+	}
+	dbExplorer.initReplicas()
+	if len(dbExplorer.replicas) > 0 && dbExplorer.healthInterval > 0 {
+		go dbExplorer.pollReplicas()
+	}
+	return dbExplorer, nil
 }
 
-func (d *DBExplorer) collectMetaInfo() (*DBExplorer, error) {
-	tablesNames := d.findTableNames()
-	if len(tablesNames) < 1 {
+// Read tables and their metadata from database.
+func (d *DBExplorer) collectMetaInfo(ctx context.Context) (*Schema, error) {
+	tableNames, err := d.dialect.Tables(ctx, d.conn())
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{
+		metadata:   make(map[string]TableMetadata, len(tableNames)),
+		rowFilters: make(map[string][]RowFilter),
+	}
+	for _, tableName := range tableNames {
+		if d.isTableExposed(tableName) {
+			schema.tables = append(schema.tables, tableName)
+		}
+	}
+	if len(schema.tables) < 1 {
 		return nil, errors.New("no tables in database")
 	}
-	d.TableNames = TablesList{Tables: tablesNames}
-	for _, tableName := range tablesNames {
-		// Collect each table metadata and persist in parent struct.
-		err := d.getColumnMetadata(tableName)
+	for _, tableName := range schema.tables {
+		// Collect each table metadata and persist in schema.
+		err := d.getColumnMetadata(ctx, schema, tableName)
 		if err != nil {
 			return nil, err
 		}
 	}
-	return d, nil
+	return schema, nil
 }
 
-// Return table names, resolved on initialization. We can suspect it to be static.
+// Return table names of current schema.
 func (d *DBExplorer) ListTables() []string {
-	return d.TableNames.Tables
-}
-
-func (d *DBExplorer) listColumns(tableName string) string {
-	columns := d.metadata[tableName].columnNames
-	return strings.Join(columns, ",")
+	return d.schema.Load().tables
 }
 
-func (d *DBExplorer) getIdColumn(tableName string) string {
-	columnsInfo := d.metadata[tableName].columnsInfo
-	for i := 0; i < len(columnsInfo); i++ {
-		if columnsInfo[i].isAutoIncrement {
-			return columnsInfo[i].fieldName
-		}
-	}
-	return "" // No candidate for id found among columns ?
-}
 func (d *DBExplorer) getUpdatePlaceholders(req *Req, entity DBEntry) (placeholders string, values []interface{}) {
-	columnNames := d.collectInsertColumns(req.table)
+	columnNames := collectInsertColumns(req)
 	values = make([]interface{}, 0, len(columnNames))
 	filtered := make([]string, 0, len(columnNames))
 	for i := 0; i < len(columnNames); i++ {
@@ -157,8 +262,8 @@ func (d *DBExplorer) getUpdatePlaceholders(req *Req, entity DBEntry) (placeholde
 	return
 }
 
-func (d *DBExplorer) collectInsertColumns(tableName string) []string {
-	columns := d.metadata[tableName].columnsInfo
+func collectInsertColumns(req *Req) []string {
+	columns := req.schema.metadata[req.table].columnsInfo
 	filtered := make([]string, 0, len(columns)-1)
 	for i := 0; i < len(columns); i++ {
 		if columns[i].isAutoIncrement {
@@ -169,31 +274,75 @@ func (d *DBExplorer) collectInsertColumns(tableName string) []string {
 	return filtered
 }
 
-func (d *DBExplorer) listInsertColumns(tableName string) string {
-	return strings.Join(d.collectInsertColumns(tableName), ",")
-}
-
 // Simple request tracking to StdOut.
 func (d *DBExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Usefull http requests tracking [-> 2024-07-17 17:58:29 [GET] : /users/1 {url.Values{}}]
-	fmt.Printf("-> %s [%s] : %s {%#v}\n", time.Now().Format(time.DateTime), r.Method, r.URL.Path, r.URL.Query())
-	d.route(w, r)
+	// Usefull http requests tracking [-> 2024-07-17 17:58:29 [GET] : /users/1 {url.Values{}} by support]
+	authenticated, err := d.authenticate(r)
+	if err != nil {
+		fmt.Fprintf(d.requestLog, "-> %s [%s] : %s {%#v} unauthenticated: %s\n", time.Now().Format(time.DateTime), r.Method, r.URL.Path, r.URL.Query(), err)
+		w.Header().Set("WWW-Authenticate", authenticateHeader)
+		reply(w, Resp(nil, http.StatusUnauthorized, err))
+		return
+	}
+	principalID := "-"
+	if principal := PrincipalFromContext(authenticated.Context()); principal != nil {
+		principalID = principal.ID
+	}
+	fmt.Fprintf(d.requestLog, "-> %s [%s] : %s {%#v} by %s\n", time.Now().Format(time.DateTime), r.Method, r.URL.Path, r.URL.Query(), principalID)
+	if d.pinWindow > 0 && r.Method != http.MethodGet {
+		d.pinPrimary(w)
+	}
+	if key := r.Header.Get(idempotencyHeader); key != "" && r.Method != http.MethodGet {
+		d.serveIdempotent(w, authenticated, key)
+		return
+	}
+	d.route(w, authenticated)
 }
 
 // -------------------------------- Router   --------------------------------------
 func (d *DBExplorer) route(w http.ResponseWriter, r *http.Request) {
-	requestedData, err := parse(r, d.metadata)
+	switch r.URL.Path {
+	case batchPath:
+		d.handleBatch(w, r)
+		return
+	case schemaReloadPath:
+		d.handleSchemaReload(w, r)
+		return
+	}
+	requestedData, err := parse(r, d.schema.Load())
 	if err != nil {
-		reply(w, Resp(nil, http.StatusInternalServerError, err))
+		reply(w, Resp(nil, http.StatusBadRequest, err))
 		return
 	}
+	var cancel context.CancelFunc
+	requestedData.ctx, cancel = d.withTimeout(requestedData.ctx, requestedData.table)
+	defer cancel()
+	requestedData.readPrimary = d.isPinned(r)
+	if allowed := d.allowedMethods(requestedData.table); !isMethodAllowed(r.Method, allowed) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		reply(w, Resp(nil, http.StatusMethodNotAllowed, errors.New(MethodNotAllowedErr)))
+		return
+	}
+	if !requestedData.isTableNamesQuery() {
+		if denied := d.authorize(requestedData.principal, requestedData.table, methodAction(r.Method)); denied != nil {
+			reply(w, Response{
+				status:  http.StatusForbidden,
+				Err:     PermissionDeniedErr,
+				Code:    CodeForbidden,
+				Details: map[string]string{"permission": denied.String()},
+			})
+			return
+		}
+	}
 	switch r.Method {
 	case http.MethodGet: // Query results by predicate from database.
 		d.handleGet(w, requestedData)
 	case http.MethodPost: // Upate existing entry in database.
 		d.handlePost(w, requestedData)
-	case http.MethodPut: // Create new entry in database.
+	case http.MethodPut: // Create new entry in database or replace existing one.
 		d.handlePut(w, requestedData)
+	case http.MethodPatch: // Partially modify existing entry.
+		d.handlePatch(w, requestedData)
 	case http.MethodDelete: // Delete entry by id provided in url path.
 		d.handleDelete(w, requestedData)
 	default: // Invalid API usage by client.
@@ -203,111 +352,152 @@ func (d *DBExplorer) route(w http.ResponseWriter, r *http.Request) {
 
 // ------------------ parse request params ---------------------
 
-func parse(r *http.Request, tableMetadataMap map[string]TableMetadata) (presult *Req, err error) {
-	p := r.URL.Path
+func parse(r *http.Request, schema *Schema) (presult *Req, err error) {
+	p := r.URL.EscapedPath() // Keep escaped to let `%2C` be part of id instead of separator.
 	tokens := strings.Split(p, "/")[1:]
 	var tableName string
-	var id int = -1
+	var id []string
 	if len(tokens) > 0 {
-		tableName = tokens[0]
+		if tableName, err = url.PathUnescape(tokens[0]); err != nil {
+			return nil, err
+		}
 	}
 	if len(tokens) > 1 && tokens[1] != "" {
-		candiate, err := strconv.Atoi(tokens[1])
-		if err != nil {
-			return nil, err
+		id = strings.Split(tokens[1], idSeparator)
+		for i := range id {
+			if id[i], err = url.PathUnescape(id[i]); err != nil {
+				return nil, err
+			}
 		}
-		id = candiate
 	}
 
+	body, rows := extractRequestBody(r, schema.metadata[tableName].columnsInfo)
 	return &Req{
-		table:  tableName,
-		id:     id,
-		params: r.URL.Query(),
-		body:   extractRequestBody(r, tableMetadataMap[tableName].columnsInfo),
+		ctx:         r.Context(),
+		schema:      schema,
+		table:       tableName,
+		id:          id,
+		params:      r.URL.Query(),
+		body:        body,
+		rows:        rows,
+		patch:       extractPatch(r),
+		principal:   PrincipalFromContext(r.Context()),
+		ifMatch:     r.Header.Get("If-Match"),
+		ifNoneMatch: r.Header.Get("If-None-Match"),
 	}, nil
 }
 
 // -------------------------------- Handlers --------------------------------------
 // Only query for some data from database: list of table names / content of specified table.
 func (d *DBExplorer) handleGet(w http.ResponseWriter, requestedData *Req) {
-	var resp Response = Resp(nil, http.StatusNotFound, errors.New("no such endpoint"))
+	var resp Response = Resp(nil, http.StatusNotFound, notFoundError(NoEndpointErr))
 	switch {
 	// We need to provide only list of table names.
 	case requestedData.isTableNamesQuery():
-		resp = Resp(map[string][]string{"tables": d.ListTables()}, http.StatusOK, nil)
+		resp = Resp(map[string][]string{"tables": d.readableTables(requestedData.principal)}, http.StatusOK, nil)
 	// we need to reply on multi-row query to database.
 	case requestedData.isTableEntriesQuery():
-		res, err := d.query(requestedData)
+		page, err := d.query(d.reader(requestedData), requestedData)
 		if err != nil {
-			resp = Resp(nil, http.StatusNotFound, err)
+			resp = d.failure(requestedData, errorStatus(err), err)
 			break
 		}
-		resp = Resp(res, http.StatusOK, nil)
+		if links := page.links(d.basePath, requestedData.table); links != "" {
+			w.Header().Set("Link", links)
+		}
+		resp = Resp(page.content, http.StatusOK, nil)
 	// Only single row was requested by id.
 	case requestedData.isByIdQuery():
-		result, err := d.queryBy(requestedData)
+		columns, err := parseFields(requestedData.params.Get(fieldsParam), requestedData.schema.metadata[requestedData.table])
+		if err != nil {
+			resp = d.failure(requestedData, errorStatus(err), err)
+			break
+		}
+		record, err := d.selectRow(d.reader(requestedData), requestedData, false)
 		if err != nil {
-			resp = Resp(nil, http.StatusNotFound, err)
+			resp = d.failure(requestedData, errorStatus(err), err)
 			break
 		}
-		resp = Resp(result, http.StatusOK, nil)
+		tag := d.entityTag(record)
+		w.Header().Set("ETag", tag)
+		if matchesETag(requestedData.ifNoneMatch, tag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		record = maskEntry(record, requestedData.schema.metadata[requestedData.table].columnsInfo)
+		stripColumns(DBEntries{record}, columns)
+		resp = Resp(map[string]DBEntry{"record": record}, http.StatusOK, nil)
 	}
 	reply(w, resp)
 }
 
 func (d *DBExplorer) handlePost(w http.ResponseWriter, requestedData *Req) {
-	result, err := d.update(requestedData)
+	result, err := d.writeRow(requestedData, d.update)
 	if err != nil {
-		reply(w, Resp(nil, http.StatusBadRequest, err))
+		reply(w, d.failure(requestedData, errorStatus(err), err))
 		return // Failed to query DB.
 	}
 	reply(w, Resp(result, http.StatusOK, nil)) // Success on DB query.
 }
 
-func extractRequestBody(r *http.Request, columnsInfo []ColumnMetadata) RequestBody {
-	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
-		return nil
+// Decode request body: single json object, or json array of objects for bulk insert.
+func extractRequestBody(r *http.Request, columnsInfo []ColumnMetadata) (RequestBody, []RequestBody) {
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete || r.Method == http.MethodPatch {
+		return nil, nil
 	}
 	rawBodyBytes, err := io.ReadAll(r.Body)
 	defer closeResources(r.Body)
 	if err != nil {
-		return nil
+		return nil, nil
+	}
+	var temp interface{} // Preliminary uhnmarshall of request body for further filtering and casting.
+	decoder := json.NewDecoder(bytes.NewReader(rawBodyBytes))
+	decoder.UseNumber() // Numbers are casted according to column type on validation.
+	if err := decoder.Decode(&temp); err != nil {
+		return nil, nil
+	}
+	switch decoded := temp.(type) {
+	case map[string]interface{}:
+		return filterRequestBody(decoded, columnsInfo), nil
+	case []interface{}:
+		rows := make([]RequestBody, len(decoded))
+		for i, item := range decoded {
+			if row, ok := item.(map[string]interface{}); ok {
+				rows[i] = filterRequestBody(row, columnsInfo)
+			} // Not an object is kept as nil row and rejected on insert.
+		}
+		return nil, rows
 	}
-	temp := make(RequestBody) // Preliminary uhnmarshall of request body for further filtering and casting.
+	return nil, nil
+}
 
-	if err := json.Unmarshal(rawBodyBytes, &temp); err != nil {
-		return nil
-	}
-	var colName string
-	// Filter unknown attributes and make explicit casting. Use known columns metadata.
+// Filter unknown attributes. Use known columns metadata.
+func filterRequestBody(temp RequestBody, columnsInfo []ColumnMetadata) RequestBody {
+	body := make(RequestBody, len(columnsInfo))
 	for i := 0; i < len(columnsInfo); i++ {
-		colName = columnsInfo[i].fieldName
-		if val, presented := temp[colName]; !presented {
-			delete(temp, colName)
-			continue
-		} else {
-			// Explicit cast to numeric.
-			if columnsInfo[i].isNumericType {
-				temp[colName] = int(val.(float64))
-				continue
-			}
+		if val, presented := temp[columnsInfo[i].fieldName]; presented {
+			body[columnsInfo[i].fieldName] = val
 		}
 	}
-	return temp
+	return body
 }
 
 func (d *DBExplorer) handlePut(w http.ResponseWriter, requestedData *Req) {
-	result, err := d.insert(requestedData)
+	if requestedData.rows != nil {
+		d.handleBulkPut(w, requestedData)
+		return
+	}
+	result, err := d.writeRow(requestedData, d.put)
 	if err != nil {
-		reply(w, Resp(nil, http.StatusNotFound, err))
+		reply(w, d.failure(requestedData, errorStatus(err), err))
 		return
 	}
 	reply(w, Resp(result, http.StatusOK, nil))
 }
 
 func (d *DBExplorer) handleDelete(w http.ResponseWriter, requestedData *Req) {
-	if result, err := d.delete(requestedData); err != nil {
-		reply(w, Resp(nil, http.StatusNotFound, err))
+	if result, err := d.writeRow(requestedData, d.delete); err != nil {
+		reply(w, d.failure(requestedData, errorStatus(err), err))
 	} else {
 		reply(w, Resp(result, http.StatusOK, nil))
 	}
@@ -315,6 +505,24 @@ func (d *DBExplorer) handleDelete(w http.ResponseWriter, requestedData *Req) {
 
 // ---------------------------- ------------------
 
+// Run operations in transaction: committed if all of them succeed, rolled back otherwise.
+// Transaction is rolled back if context is done before commit.
+func (d *DBExplorer) inTransaction(ctx context.Context, run func(tx querier) (interface{}, error)) (interface{}, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := run(d.bind(tx))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func closeResources(closer io.Closer) {
 
 	err := closer.Close()
@@ -323,6 +531,8 @@ func closeResources(closer io.Closer) {
 	}
 }
 
+// Check entity values against columns metadata. Valid values are converted
+// to database representation in place.
 func validate(entity DBEntry, columnsInfo []ColumnMetadata) error {
 	for i := 0; i < len(columnsInfo); i++ {
 		if val, presented := entity[columnsInfo[i].fieldName]; !presented {
@@ -335,23 +545,15 @@ func validate(entity DBEntry, columnsInfo []ColumnMetadata) error {
 			if !columnsInfo[i].isNullable && val == nil {
 				failed = true
 			}
-
-			isNumeric := columnsInfo[i].isNumericType
-			if val != nil {
-				switch val.(type) {
-				case string:
-					if isNumeric {
-						failed = true
-					}
-				default:
-					if !isNumeric {
-						failed = true
-					}
-				}
+			if val != nil && !failed {
+				converted, ok := columnsInfo[i].columnType.convert(val)
+				entity[columnsInfo[i].fieldName] = converted
+				failed = !ok
 			}
 
 			if failed {
-				return fmt.Errorf("field %s have invalid type", columnsInfo[i].fieldName)
+				message := fmt.Sprintf(InvalidIDTypeErrParrern, columnsInfo[i].fieldName)
+				return newValidationError(message, columnsInfo[i].fieldName, reasonInvalidType)
 			}
 		}
 	}
@@ -360,35 +562,25 @@ func validate(entity DBEntry, columnsInfo []ColumnMetadata) error {
 
 // ---------------------- Database operations ---------------------------
 
-func (e *DBExplorer) findTableNames() []string {
-	rows, err := e.db.Query(showTablesQuery)
-	defer closeResources(rows)
-	if err != nil {
-		return nil
-	}
-
-	tablesNames := make([]string, 0, 10)
-	var tableName string
-	for rows.Next() {
-		if err := rows.Scan(&tableName); err != nil {
-			return nil
-		}
-		tablesNames = append(tablesNames, tableName)
-	}
-	return tablesNames
-
-}
-
 // Perform delete from database by ID specified in http path.
-func (d *DBExplorer) delete(req *Req) (interface{}, error) {
-	if _, ok := d.metadata[req.table]; !ok {
-		return nil, errors.New(UnknownTableErr)
+func (d *DBExplorer) delete(q querier, req *Req) (interface{}, error) {
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
 	}
-	idColumn := d.getIdColumn(req.table)
-	sql := fmt.Sprintf(deleteQuery, req.table, idColumn)
+	byID, err := idPredicate(req, tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	where, args := buildWhereClause(append([]Predicate{byID}, d.rowPredicates(req)...))
+	sql := fmt.Sprintf(deleteQuery, req.table, where)
 
-	result, err := d.db.Exec(sql, req.id)
+	result, err := q.ExecContext(req.ctx, sql, args...)
 	if err != nil {
+		if errors.As(classifyError(err), new(foreignKeyError)) {
+			// Foreign key fails on delete only if row is referenced: SQLite does not tell it from invalid reference.
+			return nil, conflictError{message: ReferencedErr, reason: CodeReferenced}
+		}
 		return nil, err
 	}
 	lastID, err := result.RowsAffected()
@@ -398,66 +590,156 @@ func (d *DBExplorer) delete(req *Req) (interface{}, error) {
 	return map[string]interface{}{"deleted": lastID}, nil
 }
 
-func (d *DBExplorer) insert(req *Req) (interface{}, error) {
+func (d *DBExplorer) insert(q querier, req *Req) (interface{}, error) {
 	entity := req.body
-	tableMetadata, ok := d.metadata[req.table]
+	tableMetadata, ok := req.schema.metadata[req.table]
 	if !ok {
-		return nil, errors.New(UnknownTableErr)
-	}
-	idColumn := d.getIdColumn(req.table)
-	columns := d.collectInsertColumns(req.table)
-	columnsCount := len(columns)
-	placeholders := strings.Join(strings.Split(strings.Repeat("?", columnsCount), ""), ",")
-	sql := fmt.Sprintf(insertQuery, req.table, d.listInsertColumns(req.table), placeholders)
-	values := make([]interface{}, columnsCount)
-	for i := 0; i < columnsCount; i++ {
-		values[i] = entity[columns[i]]
-		if values[i] == nil {
-			colInfo := tableMetadata.getColumn(columns[i])
-			if !colInfo.isNullable {
-				if colInfo.isNumericType {
-					values[i] = 0
-				} else {
-					values[i] = ""
-				}
-			}
-		}
+		return nil, unknownTableError(UnknownTableErr)
 	}
-	result, err := d.db.Exec(sql, values...)
+	columns, values, err := d.insertValues(req, entity)
 	if err != nil {
 		return nil, err
 	}
-	lastID, err := result.LastInsertId()
+	sql := d.insertStatement(req.table, columns)
+	autoIncrement := tableMetadata.autoIncrementColumn()
+	lastID := int64(0)
+	if autoIncrement != "" && d.dialect.Returning() {
+		sql += fmt.Sprintf(returningClause, "`"+autoIncrement+"`")
+		if err := q.QueryRowContext(req.ctx, sql, values...).Scan(&lastID); err != nil {
+			return nil, err
+		}
+		return createdKey(tableMetadata, entity, lastID), nil
+	}
+	result, err := q.ExecContext(req.ctx, sql, values...)
 	if err != nil {
 		return nil, err
 	}
-	return map[string]interface{}{idColumn: lastID}, nil
+	if autoIncrement != "" {
+		if lastID, err = result.LastInsertId(); err != nil {
+			return nil, err
+		}
+	}
+	return createdKey(tableMetadata, entity, lastID), nil
+}
+
+// Columns listed in insert statement: all except auto-incremental plus row filter columns.
+func insertColumns(req *Req) []string {
+	columns := collectInsertColumns(req)
+	for _, filter := range req.schema.rowFilters[req.table] {
+		if !slices.Contains(columns, filter.column) {
+			columns = append(columns, filter.column) // Scope column may be hidden from client.
+		}
+	}
+	return columns
+}
+
+// Insert statement of single row with placeholders of columns. Row of default values only
+// is inserted with empty column list of dialect.
+func (d *DBExplorer) insertStatement(table string, columns []string) string {
+	if len(columns) == 0 {
+		return fmt.Sprintf(insertDefaultsQuery, table, d.dialect.DefaultValues())
+	}
+	placeholders := strings.Join(strings.Split(strings.Repeat("?", len(columns)), ""), ",")
+	return fmt.Sprintf(insertQuery, table, "`"+strings.Join(columns, "`,`")+"`", placeholders)
+}
+
+// Validate entity to be inserted and collect columns of insert statement with their values.
+// Missing columns with declared default are left out to be filled by database,
+// missing values of other not nullable columns are replaced with zero value of column type.
+func (d *DBExplorer) insertValues(req *Req, entity DBEntry) ([]string, []interface{}, error) {
+	if entity == nil {
+		return nil, nil, badRequestError(InvalidBodyErr) // Missing body, invalid json or not an object.
+	}
+	tableMetadata := req.schema.metadata[req.table]
+	for i := 0; i < len(tableMetadata.columnsInfo); i++ {
+		if tableMetadata.columnsInfo[i].isAutoIncrement {
+			delete(entity, tableMetadata.columnsInfo[i].fieldName) // Generated by database, ignored on insert.
+		}
+	}
+	if err := validate(entity, tableMetadata.columnsInfo); err != nil {
+		return nil, nil, err
+	}
+	if err := d.enforceRowFilters(req, entity, true); err != nil {
+		return nil, nil, err
+	}
+	candidates := insertColumns(req)
+	columns := make([]string, 0, len(candidates))
+	values := make([]interface{}, 0, len(candidates))
+	for _, column := range candidates {
+		value, presented := entity[column]
+		colInfo := tableMetadata.getColumn(column)
+		if !presented && colInfo.hasDefault {
+			continue
+		}
+		if value == nil && !colInfo.isNullable {
+			value = colInfo.columnType.zeroValue()
+		}
+		columns = append(columns, column)
+		values = append(values, value)
+	}
+	return columns, values, nil
 }
 
-func (d *DBExplorer) update(req *Req) (interface{}, error) {
+// Id of created row: generated by database (`lastID`) or submitted by client.
+func createdKey(tableMetadata TableMetadata, entity DBEntry, lastID int64) map[string]interface{} {
+	created := make(map[string]interface{}, len(tableMetadata.primaryKey))
+	for _, column := range tableMetadata.primaryKey {
+		created[column] = entity[column]
+	}
+	if autoIncrement := tableMetadata.autoIncrementColumn(); autoIncrement != "" {
+		created[autoIncrement] = lastID
+	}
+	return created
+}
+
+func (d *DBExplorer) update(q querier, req *Req) (interface{}, error) {
 	entity := req.body
-	tableMetadata, ok := d.metadata[req.table]
-	if !ok || entity == nil {
-		return nil, errors.New(UnknownTableErr)
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	if entity == nil {
+		return nil, badRequestError(InvalidBodyErr) // Missing body, invalid json or not an object.
+	}
+	// Primary key can not be updated for existing record.
+	for _, column := range tableMetadata.primaryKey {
+		if _, presented := entity[column]; presented {
+			return nil, newValidationError(fmt.Sprintf(InvalidIDTypeErrParrern, column), column, reasonReadOnly)
+		}
 	}
 	if hasError := validate(entity, tableMetadata.columnsInfo); hasError != nil {
 		return nil, hasError
 	}
-	idValue := req.id
-	idColumn := d.getIdColumn(req.table)
-	columns := d.collectInsertColumns(req.table)
-	columnsCount := len(columns)
-	updatePlaceholders, updateValues := d.getUpdatePlaceholders(req, entity)
-	if updatePlaceholders == BadRequest {
-		return nil, errors.New("bad request")
+	if err := d.enforceRowFilters(req, entity, false); err != nil {
+		return nil, err
 	}
-	sql := fmt.Sprintf(updateQuery, req.table, updatePlaceholders, idColumn)
-	values := make([]interface{}, columnsCount)
-	for i := 0; i < columnsCount; i++ {
-		values[i] = entity[columns[i]]
+	return d.updateRow(q, req, entity, nil)
+}
+
+// Update row addressed by request id with validated entity.
+// Columns listed in `defaults` are reset to their default values.
+func (d *DBExplorer) updateRow(q querier, req *Req, entity DBEntry, defaults []string) (interface{}, error) {
+	byID, err := idPredicate(req, req.schema.metadata[req.table])
+	if err != nil {
+		return nil, err
 	}
-	updateValues = append(updateValues, idValue)
-	result, err := d.db.Exec(sql, updateValues...)
+	updatePlaceholders, updateValues := d.getUpdatePlaceholders(req, entity)
+	if updatePlaceholders == BadRequest && len(defaults) == 0 {
+		return nil, badRequestError(NothingToUpdateErr)
+	}
+	assignments := make([]string, 0, len(defaults)+1)
+	if updatePlaceholders != BadRequest {
+		assignments = append(assignments, updatePlaceholders)
+	}
+	for _, column := range defaults {
+		columnInfo := req.schema.metadata[req.table].getColumn(column)
+		assignments = append(assignments, "`"+column+"` = "+d.dialect.DefaultValue(columnInfo))
+	}
+	updatePlaceholders = strings.Join(assignments, ", ")
+	where, args := buildWhereClause(append([]Predicate{byID}, d.rowPredicates(req)...))
+	sql := fmt.Sprintf(updateQuery, req.table, updatePlaceholders, where)
+	updateValues = append(updateValues, args...)
+	result, err := q.ExecContext(req.ctx, sql, updateValues...)
 	if err != nil {
 		return nil, err
 	}
@@ -468,45 +750,187 @@ func (d *DBExplorer) update(req *Req) (interface{}, error) {
 	return map[string]interface{}{"updated": lastID}, nil
 }
 
-func (d *DBExplorer) queryBy(r *Req) (interface{}, error) {
+func (d *DBExplorer) queryBy(q querier, r *Req) (interface{}, error) {
 	if r.table == "" {
-		return nil, errors.New("bad request")
+		return nil, unknownTableError(UnknownTableErr)
 	}
 
-	tableMetadata, ok := d.metadata[r.table]
+	tableMetadata, ok := r.schema.metadata[r.table]
 	if !ok {
-		return nil, errors.New(UnknownTableErr)
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	columns, err := parseFields(r.params.Get(fieldsParam), tableMetadata)
+	if err != nil {
+		return nil, err
 	}
-	columns := d.listColumns(r.table)
-	sql := fmt.Sprintf(selectByIdQuery, columns, r.table, tableMetadata.columnNames[0])
-	row := d.db.QueryRow(sql, r.id)
-	rowResult := newRowResult(tableMetadata)
+	byID, err := idPredicate(r, tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	where, args := buildWhereClause(append([]Predicate{byID}, d.rowPredicates(r)...))
+	sql := fmt.Sprintf(selectByIdQuery, listColumns(columns), r.table, where)
+	row := q.QueryRowContext(r.ctx, sql, args...)
+	rowResult := newRowResult(columns)
 	return rowResult.handleSingleRowResult(row)
 }
 
-func (d *DBExplorer) query(r *Req) (result interface{}, err error) {
+// Read all columns of row addressed by id with actual values of masked columns.
+// Row is locked till the end of transaction if `lock` is set.
+func (d *DBExplorer) selectRow(q querier, r *Req, lock bool) (DBEntry, error) {
+	tableMetadata, ok := r.schema.metadata[r.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	byID, err := idPredicate(r, tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	where, args := buildWhereClause(append([]Predicate{byID}, d.rowPredicates(r)...))
+	sql := fmt.Sprintf(selectByIdQuery, listColumns(tableMetadata.columnsInfo), r.table, where)
+	if lock {
+		sql += d.dialect.LockClause()
+	}
+	rowResult := newRowResult(tableMetadata.columnsInfo)
+	rowResult.unmasked = true
+	result, err := rowResult.handleSingleRowResult(q.QueryRowContext(r.ctx, sql, args...))
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]DBEntry)["record"], nil
+}
+
+func (d *DBExplorer) query(q querier, r *Req) (*Page, error) {
 
-	tableMetadata, known := d.metadata[r.table] // Should be ok, cause table is known.
+	tableMetadata, known := r.schema.metadata[r.table] // Should be ok, cause table is known.
 	if !known {
-		return nil, errors.New(UnknownTableErr)
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	filters, err := parseFilters(r.params, tableMetadata)
+	if err != nil {
+		return nil, err
 	}
-	sql := fmt.Sprintf(selectQuery, d.listColumns(r.table), r.table)
-	limit, offset := defaultLimit, defaultOffest
+	columns, err := parseFields(r.params.Get(fieldsParam), tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := parseOrder(r.params.Get(orderParam), tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	count, err := parseCount(r.params.Get(countParam))
+	if err != nil {
+		return nil, err
+	}
+	limit, offset := d.defaultLimit, defaultOffest
 	if len(r.params) > 0 {
-		if tempLimit, err := strconv.Atoi(r.params.Get("limit")); err == nil {
+		if tempLimit, err := strconv.Atoi(r.params.Get("limit")); err == nil && tempLimit >= 0 {
 			limit = tempLimit
 		}
-		if tempOffset, err := strconv.Atoi(r.params.Get("offset")); err == nil {
+		if d.maxLimit > 0 {
+			limit = min(limit, d.maxLimit)
+		}
+		if tempOffset, err := strconv.Atoi(r.params.Get("offset")); err == nil && tempOffset >= 0 {
 			offset = tempOffset
 		}
 	}
+	predicates := append(filterPredicates(filters), d.rowPredicates(r)...)
+	if count == countEstimated && len(r.schema.rowFilters[r.table]) > 0 {
+		count = countExact // Catalog knows size of whole table only: it must not leak rows out of principal scope.
+	}
+	var total int64
+	switch count {
+	case countExact:
+		where, args := buildWhereClause(predicates)
+		err = q.QueryRowContext(r.ctx, fmt.Sprintf(countQuery, r.table, where), args...).Scan(&total)
+	case countEstimated:
+		query, args := d.dialect.EstimatedCountQuery(r.table)
+		err = q.QueryRowContext(r.ctx, query, args...).Scan(&total)
+	}
+	if err != nil {
+		return nil, err
+	}
+	useCursor := r.params.Has(cursorParam)
+	if useCursor {
+		if r.params.Has("offset") {
+			return nil, badRequestError(CursorWithOffsetErr)
+		}
+		if raw := r.params.Get(cursorParam); raw != "" {
+			cursor, err := parseCursor(raw, orders, tableMetadata)
+			if err != nil {
+				return nil, err
+			}
+			predicates = append(predicates, cursor.predicate(orders))
+		}
+	}
+	// Sort columns are selected even if not projected: they are required to build next cursor.
+	selected := columns
+	if useCursor {
+		selected = withOrderColumns(columns, orders, tableMetadata)
+	}
+	where, args := buildWhereClause(predicates)
+	sql := fmt.Sprintf(selectQuery, listColumns(selected), r.table, where, buildOrderClause(orders, d.dialect))
 
-	rows, err := d.db.Query(sql, limit, offset)
-	defer closeResources(rows)
+	// One extra record is requested to know whether there is a next page.
+	rows, err := q.QueryContext(r.ctx, sql, append(args, limit+1, offset)...)
 	if err != nil {
 		return nil, err
 	}
-	rowResult := newRowResult(tableMetadata)
-	err = rowResult.handleMultiRowResult(rows)
-	return map[string]interface{}{"records": rowResult.entries}, err
+	defer closeResources(rows)
+	rowResult := newRowResult(selected)
+	if err = rowResult.handleMultiRowResult(rows); err != nil {
+		return nil, err
+	}
+	records := rowResult.entries
+	hasMore := limit > 0 && len(records) > limit // Zero-size page (`limit=0&count=exact`) has no next page to follow.
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	page := &Page{
+		content: map[string]interface{}{"records": records},
+		params:  r.params,
+		limit:   limit,
+		offset:  offset,
+		hasMore: hasMore,
+	}
+	if useCursor {
+		var nextCursor interface{}
+		if hasMore && len(records) > 0 {
+			page.nextCursor = newCursor(orders, records[len(records)-1])
+			nextCursor = page.nextCursor
+		}
+		page.content[nextCursorKey] = nextCursor
+		page.useCursor = true
+		stripColumns(records, columns)
+	}
+	if count != "" {
+		page.content["total"] = total
+		page.content["limit"] = limit
+		page.content["has_more"] = hasMore
+		if !useCursor {
+			page.content["offset"] = offset
+		}
+	}
+	return page, nil
+}
+
+// Build condition matching row by id from request path against primary key of table.
+func idPredicate(req *Req, tableMetadata TableMetadata) (Predicate, error) {
+	if len(tableMetadata.primaryKey) == 0 {
+		return Predicate{}, badRequestError(fmt.Sprintf(NoPrimaryKeyErr, req.table))
+	}
+	if len(req.id) != len(tableMetadata.primaryKey) {
+		return Predicate{}, badRequestError(InvalidIDErr)
+	}
+	conditions := make([]string, len(req.id))
+	args := make([]interface{}, len(req.id))
+	for i, column := range tableMetadata.primaryKey {
+		if tableMetadata.getColumn(column).columnType.isNumeric() {
+			if _, err := strconv.ParseFloat(req.id[i], 64); err != nil {
+				return Predicate{}, badRequestError(InvalidIDErr)
+			}
+		}
+		conditions[i] = "`" + column + "` = ?"
+		args[i] = req.id[i]
+	}
+	return Predicate{sql: strings.Join(conditions, " AND "), args: args}, nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQL flavour of database. Statements are built in canonical form: identifiers quoted with backticks,
+// arguments bound with `?`. Dialect translates them before execution and knows how to introspect catalog.
+type Dialect interface {
+	Name() string
+	QuoteIdent(name string) string // Quoted identifier: `name` for MySQL, "name" for PostgreSQL.
+	Placeholder(n int) string      // Placeholder of n-th argument starting from 1: `?` or `$1`.
+	// Tables of current database / schema.
+	Tables(ctx context.Context, q querier) ([]string, error)
+	// Columns of table in order of declaration.
+	Columns(ctx context.Context, q querier, table string) ([]ColumnMetadata, error)
+	// Query listing columns of all tables: checksum of its result changes with every migration.
+	SchemaVersionQuery() string
+	// Suffix of sort key making NULL the lowest value, as MySQL sorts it.
+	NullsOrder(descending bool) string
+	// Query of approximate number of rows of table with its arguments.
+	EstimatedCountQuery(table string) (string, []interface{})
+	// Suffix of SELECT keeping read row locked till the end of transaction.
+	LockClause() string
+	// Expression of UPDATE assignment resetting column to its default value.
+	DefaultValue(column ColumnMetadata) string
+	// Suffix of INSERT of row with default values only: no columns are listed.
+	DefaultValues() string
+	// Generated keys are reported by `INSERT ... RETURNING` instead of LastInsertId.
+	Returning() bool
+	// Column type for arbitrary bytes.
+	BinaryType() string
+}
+
+// Use SQL dialect of database. Detected from driver by default, MySQL if driver is not recognized.
+func WithDialect(dialect Dialect) Option {
+	return func(d *DBExplorer) {
+		d.dialect = dialect
+	}
+}
+
+// Dialect of database by type of its driver: `*mysql.MySQLDriver`, `*pq.Driver`, `*stdlib.Driver` of pgx,
+// `*sqlite.Driver`.
+func detectDialect(db *sql.DB) Dialect {
+	driver := fmt.Sprintf("%T", db.Driver())
+	switch {
+	case strings.HasPrefix(driver, "*pq."), strings.HasPrefix(driver, "*stdlib."), strings.Contains(driver, "pgx"):
+		return PostgresDialect{}
+	case strings.HasPrefix(driver, "*sqlite"):
+		return SQLiteDialect{}
+	}
+	return MySQLDialect{}
+}
+
+// Translate canonical statement into dialect: backtick quoted identifiers are quoted by dialect,
+// `?` are replaced with dialect placeholders. String literals are kept as is.
+func rebind(dialect Dialect, query string) string {
+	var (
+		result strings.Builder
+		ident  strings.Builder
+		n      int
+	)
+	result.Grow(len(query) + 16)
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; ch {
+		case '\'':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == '\'' {
+					if end+1 < len(query) && query[end+1] == '\'' { // Escaped quote.
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end, len(query)-1)
+			result.WriteString(query[i : end+1])
+			i = end
+		case '`':
+			ident.Reset()
+			for i++; i < len(query); i++ {
+				if query[i] == '`' {
+					if i+1 < len(query) && query[i+1] == '`' { // Escaped backtick.
+						ident.WriteByte('`')
+						i++
+						continue
+					}
+					break
+				}
+				ident.WriteByte(query[i])
+			}
+			result.WriteString(dialect.QuoteIdent(ident.String()))
+		case '?':
+			n++
+			result.WriteString(dialect.Placeholder(n))
+		default:
+			result.WriteByte(ch)
+		}
+	}
+	return result.String()
+}
+
+// Querier executing canonical statements in SQL of dialect.
+type boundQuerier struct {
+	q       querier
+	dialect Dialect
+}
+
+func (b boundQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return b.q.ExecContext(ctx, rebind(b.dialect, query), args...)
+}
+
+func (b boundQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return b.q.QueryContext(ctx, rebind(b.dialect, query), args...)
+}
+
+func (b boundQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return b.q.QueryRowContext(ctx, rebind(b.dialect, query), args...)
+}
+
+// Connection pool accepting canonical statements.
+func (d *DBExplorer) conn() querier {
+	return d.bind(d.db)
+}
+
+// Make connection or transaction accept canonical statements.
+func (d *DBExplorer) bind(q querier) querier {
+	return boundQuerier{q: q, dialect: d.dialect}
+}
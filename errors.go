@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// ------------------ machine readable error codes ------------
+	CodeBadRequest            = "bad_request"
+	CodeValidation            = "validation_failed"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeNotFound              = "not_found"
+	CodeUnknownTable          = "unknown_table"
+	CodeUnknownDatabase       = "unknown_database"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeConflict              = "conflict"
+	CodeDuplicateKey          = "duplicate_key"
+	CodeReferenced            = "referenced_record"
+	CodePreconditionFailed    = "precondition_failed"
+	CodeUnsupportedMedia      = "unsupported_media_type"
+	CodeUnprocessable         = "unprocessable_entity"
+	CodeForeignKey            = "foreign_key_violation"
+	CodePreconditionRequired  = "precondition_required"
+	CodeInternal              = "internal_error"
+	CodeTimeout               = "timeout"
+	CodeIdempotencyInProgress = "idempotency_key_in_progress"
+	CodeSchemaChanged         = "schema_changed"
+	// ------------------ database failures ----------------------
+	DuplicateKeyErr  = "record with the same key already exists"
+	ForeignKeyErr    = "referenced record does not exist"
+	ReferencedErr    = "record is referenced by other records"
+	InvalidValueErr  = "invalid value"
+	TimeoutErr       = "query timed out"
+	SchemaChangedErr = "database schema has changed, retry request"
+	// ------------------ reasons of field errors -----------------
+	reasonInvalidType  = "invalid type"
+	reasonInvalidValue = "invalid value"
+	reasonRequired     = "required"
+	reasonIDMismatch   = "does not match id"
+	reasonReadOnly     = "can not be changed"
+	// ------------------ database error messages -----------------
+	columnPrefix     = "column "             // Followed by quoted column name.
+	constraintPrefix = "constraint failed: " // Followed by `table.column` in SQLite errors.
+)
+
+// Error with known http status and machine readable code.
+type codedError interface {
+	error
+	status() HTTPStatus
+	code() string
+}
+
+// Error caused by malformed client input: unknown column, invalid operator etc.
+// Replied with `400 Bad Request`.
+type badRequestError string
+
+func (e badRequestError) Error() string      { return string(e) }
+func (e badRequestError) status() HTTPStatus { return http.StatusBadRequest }
+func (e badRequestError) code() string       { return CodeBadRequest }
+
+// Submitted entity does not fit columns. Replied with `400 Bad Request` and reason per field.
+type validationError struct {
+	message string
+	fields  map[string]string // Reason per invalid field.
+}
+
+func newValidationError(message, field, reason string) validationError {
+	return validationError{message: message, fields: map[string]string{field: reason}}
+}
+
+func (e validationError) Error() string      { return e.message }
+func (e validationError) status() HTTPStatus { return http.StatusBadRequest }
+func (e validationError) code() string       { return CodeValidation }
+
+// Operation is not permitted to principal. Replied with `403 Forbidden`.
+type forbiddenError string
+
+func (e forbiddenError) Error() string      { return string(e) }
+func (e forbiddenError) status() HTTPStatus { return http.StatusForbidden }
+func (e forbiddenError) code() string       { return CodeForbidden }
+
+// No row matches request. Replied with `404 Not Found`.
+type notFoundError string
+
+func (e notFoundError) Error() string      { return string(e) }
+func (e notFoundError) status() HTTPStatus { return http.StatusNotFound }
+func (e notFoundError) code() string       { return CodeNotFound }
+
+// Table is missing or not exposed. Replied with `404 Not Found`.
+type unknownTableError string
+
+func (e unknownTableError) Error() string      { return string(e) }
+func (e unknownTableError) status() HTTPStatus { return http.StatusNotFound }
+func (e unknownTableError) code() string       { return CodeUnknownTable }
+
+// Database is not mounted. Replied with `404 Not Found`.
+type unknownDatabaseError string
+
+func (e unknownDatabaseError) Error() string      { return string(e) }
+func (e unknownDatabaseError) status() HTTPStatus { return http.StatusNotFound }
+func (e unknownDatabaseError) code() string       { return CodeUnknownDatabase }
+
+// Request conflicts with current state. Replied with `409 Conflict`.
+type conflictError struct {
+	message string
+	reason  string // Machine readable code.
+}
+
+func (e conflictError) Error() string      { return e.message }
+func (e conflictError) status() HTTPStatus { return http.StatusConflict }
+func (e conflictError) code() string       { return e.reason }
+
+// Row references missing row of other table. Replied with `422 Unprocessable Entity`.
+type foreignKeyError string
+
+func (e foreignKeyError) Error() string      { return string(e) }
+func (e foreignKeyError) status() HTTPStatus { return http.StatusUnprocessableEntity }
+func (e foreignKeyError) code() string       { return CodeForeignKey }
+
+// Row does not match `If-Match` of request. Replied with `412 Precondition Failed`.
+type preconditionFailedError string
+
+func (e preconditionFailedError) Error() string      { return string(e) }
+func (e preconditionFailedError) status() HTTPStatus { return http.StatusPreconditionFailed }
+func (e preconditionFailedError) code() string       { return CodePreconditionFailed }
+
+// Write without `If-Match` while it is required. Replied with `428 Precondition Required`.
+type preconditionRequiredError string
+
+func (e preconditionRequiredError) Error() string      { return string(e) }
+func (e preconditionRequiredError) status() HTTPStatus { return http.StatusPreconditionRequired }
+func (e preconditionRequiredError) code() string       { return CodePreconditionRequired }
+
+// Query was cancelled by timeout. Replied with `504 Gateway Timeout`.
+type timeoutError string
+
+func (e timeoutError) Error() string      { return string(e) }
+func (e timeoutError) status() HTTPStatus { return http.StatusGatewayTimeout }
+func (e timeoutError) code() string       { return CodeTimeout }
+
+// Query refers to column or table which no longer exists. Schema is reloaded and request may be retried.
+// Replied with `503 Service Unavailable`.
+type schemaChangedError string
+
+func (e schemaChangedError) Error() string      { return string(e) }
+func (e schemaChangedError) status() HTTPStatus { return http.StatusServiceUnavailable }
+func (e schemaChangedError) code() string       { return CodeSchemaChanged }
+
+// Resolve http status of error: status of typed error (database failures are recognized by error number),
+// `500 Internal Server Error` otherwise.
+func errorStatus(err error) HTTPStatus {
+	var coded codedError
+	if errors.As(classifyError(err), &coded) {
+		return coded.status()
+	}
+	return http.StatusInternalServerError
+}
+
+// Machine readable code of error replied with status.
+func errorCode(err error, status HTTPStatus) string {
+	var coded codedError
+	if errors.As(classifyError(err), &coded) && coded.status() == status {
+		return coded.code()
+	}
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	return CodeInternal
+}
+
+// Reasons per field of validation error. Nil for other errors.
+func errorFields(err error) map[string]string {
+	var invalid validationError
+	if errors.As(classifyError(err), &invalid) {
+		return invalid.fields
+	}
+	return nil
+}
+
+// Mentioned column in database error message: `Column 'title' cannot be null`,
+// `null value in column "title" of relation "items"`, `NOT NULL constraint failed: items.title`.
+func columnOfError(message string) string {
+	for i := 0; i+len(columnPrefix) < len(message); i++ {
+		if !strings.EqualFold(message[i:i+len(columnPrefix)], columnPrefix) {
+			continue
+		}
+		quoted := message[i+len(columnPrefix):]
+		if quoted[0] != '\'' && quoted[0] != '"' {
+			continue
+		}
+		if end := strings.IndexAny(quoted[1:], `'"`); end > 0 {
+			return quoted[1 : end+1]
+		}
+	}
+	if _, rest, found := strings.Cut(message, constraintPrefix); found {
+		table, column, found := strings.Cut(rest, ".")
+		column = column[:len(column)-len(strings.TrimLeftFunc(column, isWordRune))]
+		if found && table != "" && strings.TrimLeftFunc(table, isWordRune) == "" && column != "" {
+			return column
+		}
+	}
+	return ""
+}
+
+// Letter, digit or underscore of identifier.
+func isWordRune(r rune) bool {
+	return r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+// Translate database and context failures into typed errors. Other errors are returned as is.
+func classifyError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return timeoutError(TimeoutErr)
+	}
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return classifySQLState(stateErr.SQLState(), err)
+	}
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		return classifySQLiteCode(liteErr.Code(), err)
+	}
+	var dbErr *mysql.MySQLError
+	if !errors.As(err, &dbErr) {
+		return err
+	}
+	switch dbErr.Number {
+	case 1062: // ER_DUP_ENTRY
+		return conflictError{message: DuplicateKeyErr, reason: CodeDuplicateKey}
+	case 1451: // ER_ROW_IS_REFERENCED_2
+		return conflictError{message: ReferencedErr, reason: CodeReferenced}
+	case 1452: // ER_NO_REFERENCED_ROW_2
+		return foreignKeyError(ForeignKeyErr)
+	case 1048, 1264, 1366, 1406, 1265: // Null, out of range, incorrect, too long, truncated value.
+		if column := columnOfError(dbErr.Message); column != "" {
+			return newValidationError(InvalidValueErr+" of field "+column, column, reasonInvalidValue)
+		}
+		return badRequestError(InvalidValueErr)
+	case 1205, 3024: // Lock wait timeout, max_execution_time exceeded.
+		return timeoutError(TimeoutErr)
+	case 1054, 1146: // Unknown column, unknown table: schema was changed after it was loaded.
+		return schemaChangedError(SchemaChangedErr)
+	}
+	return err
+}
+
+// Error of PostgreSQL driver (`*pq.Error`, `*pgconn.PgError`) reporting SQLSTATE code.
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// Translate failure reported by SQLSTATE code. Other errors are returned as is.
+func classifySQLState(state string, err error) error {
+	switch state {
+	case "23505": // unique_violation
+		return conflictError{message: DuplicateKeyErr, reason: CodeDuplicateKey}
+	case "23503": // foreign_key_violation: on delete of referenced row or on insert of referencing one.
+		if strings.Contains(err.Error(), "update or delete on") {
+			return conflictError{message: ReferencedErr, reason: CodeReferenced}
+		}
+		return foreignKeyError(ForeignKeyErr)
+	case "23502", "22001", "22003", "22P02", "22007", "22008": // Null, too long, out of range, invalid value.
+		if column := columnOfError(err.Error()); column != "" {
+			return newValidationError(InvalidValueErr+" of field "+column, column, reasonInvalidValue)
+		}
+		return badRequestError(InvalidValueErr)
+	case "57014", "55P03": // Statement timeout, lock not available.
+		return timeoutError(TimeoutErr)
+	case "42703", "42P01": // Undefined column, undefined table.
+		return schemaChangedError(SchemaChangedErr)
+	}
+	return err
+}
+
+// Error of SQLite driver (`*sqlite.Error`) reporting extended result code.
+type sqliteError interface {
+	error
+	Code() int
+}
+
+// Translate failure reported by SQLite result code. Other errors are returned as is.
+func classifySQLiteCode(code int, err error) error {
+	switch code {
+	case 1555, 2067: // SQLITE_CONSTRAINT_PRIMARYKEY, SQLITE_CONSTRAINT_UNIQUE
+		return conflictError{message: DuplicateKeyErr, reason: CodeDuplicateKey}
+	case 787: // SQLITE_CONSTRAINT_FOREIGNKEY: the same code on delete of referenced row and on invalid reference.
+		return foreignKeyError(ForeignKeyErr)
+	case 1299, 275: // SQLITE_CONSTRAINT_NOTNULL, SQLITE_CONSTRAINT_CHECK
+		if column := columnOfError(err.Error()); column != "" {
+			return newValidationError(InvalidValueErr+" of field "+column, column, reasonInvalidValue)
+		}
+		return badRequestError(InvalidValueErr)
+	}
+	switch code & 0xff { // Primary result code.
+	case 5, 6: // SQLITE_BUSY, SQLITE_LOCKED
+		return timeoutError(TimeoutErr)
+	case 1: // SQLITE_ERROR
+		if strings.Contains(err.Error(), "no such column") || strings.Contains(err.Error(), "no such table") {
+			return schemaChangedError(SchemaChangedErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	etagLength       = 16 // Bytes of HMAC-SHA256 used as entity tag.
+	etagKeyPurpose   = "etag"
+	anyTag           = "*"
+	weakTagPrefix    = "W/"
+	PreconditionErr  = "record was modified: If-Match does not match"
+	IfMatchNeededErr = "If-Match header is required"
+)
+
+// Reject writes of rows by id without `If-Match` header with `428 Precondition Required`.
+func WithRequiredIfMatch() Option {
+	return func(d *DBExplorer) {
+		d.requireIfMatch = true
+	}
+}
+
+// Key of signatures explorer issues to clients: entity tags and read-your-writes pins. Random key generated on start suits single
+// instance only: instances behind load balancer have to share key to accept each other's tags and pins.
+func WithSigningKey(key []byte) Option {
+	return func(d *DBExplorer) {
+		d.signingKey = key
+	}
+}
+
+// Generate signing key unless it is configured, and derive keys of signatures from it.
+func (d *DBExplorer) initSigningKey() error {
+	if len(d.signingKey) == 0 {
+		d.signingKey = make([]byte, sha256.Size)
+		if _, err := rand.Read(d.signingKey); err != nil {
+			return err
+		}
+	}
+	d.etagKey = deriveKey(d.signingKey, etagKeyPurpose)
+	return nil
+}
+
+// Separate key per purpose, so signature of one kind is never valid as another.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Strong entity tag of row: keyed hash of json representation of its actual values. Masked columns are hashed
+// before masking, so their writes change the tag, and the key keeps their values from being guessed by hashing candidates.
+func (d *DBExplorer) entityTag(record DBEntry) string {
+	encoded, _ := json.Marshal(record) // Keys of map are sorted, representation is stable.
+	mac := hmac.New(sha256.New, d.etagKey)
+	mac.Write(encoded)
+	return `"` + hex.EncodeToString(mac.Sum(nil)[:etagLength]) + `"`
+}
+
+// Match entity tag against `If-Match` / `If-None-Match` list. Weak comparison ignores `W/` prefix.
+func matchesETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == anyTag {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, weakTagPrefix)
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Check `If-Match` of request against current state of row. Row stays locked until end of transaction,
+// so it can not be modified between check and write.
+func (d *DBExplorer) checkPrecondition(q querier, req *Req) error {
+	if req.ifMatch == "" {
+		if d.requireIfMatch {
+			return preconditionRequiredError(IfMatchNeededErr)
+		}
+		return nil
+	}
+	record, err := d.selectRow(q, req, true)
+	if err != nil {
+		var notFound notFoundError
+		if errors.As(err, &notFound) {
+			return preconditionFailedError(PreconditionErr) // Nothing matches missing row.
+		}
+		return err
+	}
+	if !matchesETag(req.ifMatch, d.entityTag(record), false) {
+		return preconditionFailedError(PreconditionErr)
+	}
+	return nil
+}
+
+// Run write of row addressed by id. Precondition check and write share transaction.
+func (d *DBExplorer) writeRow(req *Req, write func(q querier, req *Req) (interface{}, error)) (interface{}, error) {
+	if !req.isByIdQuery() || (req.ifMatch == "" && !d.requireIfMatch) {
+		return write(d.conn(), req)
+	}
+	return d.inTransaction(req.ctx, func(tx querier) (interface{}, error) {
+		if err := d.checkPrecondition(tx, req); err != nil {
+			return nil, err
+		}
+		return write(tx, req)
+	})
+}
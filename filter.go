@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ------------------ filter operators ------------------------
+	opEq   = "eq"   // column = value (or IS NULL for `null`)
+	opNe   = "ne"   // column <> value (or IS NOT NULL for `null`)
+	opGt   = "gt"   // column > value
+	opGte  = "gte"  // column >= value
+	opLt   = "lt"   // column < value
+	opLte  = "lte"  // column <= value
+	opLike = "like" // column LIKE value
+	opIn   = "in"   // column IN (value, value, ...)
+	//-------------------------------------------------------------
+	nullFilterValue     = "null" // Literal value to query NULL columns: `?updated=null`
+	inValuesSeparator   = ","    // Separator of values for `in` operator: `?id[in]=1,2,3`
+	UnknownColumnErr    = "unknown column %s"
+	UnknownOperatorErr  = "unknown operator %s"
+	InvalidFilterErr    = "invalid filter %s"
+	EmptyFilterValueErr = "empty value for filter %s"
+	LikeNotTextErr      = "operator like is not applicable to column %s: it is not text"
+)
+
+// Single predicate requested by client via query string: `?title[like]=mem%`.
+type Filter struct {
+	column   string
+	operator string
+	values   []string
+	args     []interface{} // Values converted by column type to be bound to predicate.
+}
+
+// Query string parameters which are not treated as column filters.
+func isReservedParam(name string) bool {
+	switch name {
+	case "limit", "offset", orderParam, fieldsParam, cursorParam, countParam:
+		return true
+	}
+	return false
+}
+
+// Split query key into column name and operator: `id[gt]` -> (`id`, `gt`), `id` -> (`id`, `eq`).
+func splitFilterKey(key string) (column, operator string, err error) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 {
+		return key, opEq, nil
+	}
+	if open == 0 || !strings.HasSuffix(key, "]") {
+		return "", "", badRequestError(fmt.Sprintf(InvalidFilterErr, key))
+	}
+	return key[:open], key[open+1 : len(key)-1], nil
+}
+
+// Collect filters from query string. Only known columns and operators are accepted.
+func parseFilters(params url.Values, tableMetadata TableMetadata) ([]Filter, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !isReservedParam(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys) // Deterministic order of predicates in generated query.
+
+	filters := make([]Filter, 0, len(keys))
+	for _, key := range keys {
+		column, operator, err := splitFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		colInfo, known := tableMetadata.hash[column]
+		if !known {
+			return nil, badRequestError(fmt.Sprintf(UnknownColumnErr, column))
+		}
+		if colInfo.mask != nil {
+			return nil, badRequestError(fmt.Sprintf(MaskedColumnErr, column))
+		}
+		for _, raw := range params[key] {
+			filter := Filter{column: column, operator: operator, values: []string{raw}}
+			if operator == opIn {
+				filter.values = strings.Split(raw, inValuesSeparator)
+			}
+			if err := filter.check(colInfo); err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+// Verify operator and values are applicable to column. Values are converted by column type
+// the same way as submitted values: `?done=true` matches boolean column, `?id=abc` is rejected.
+func (f *Filter) check(colInfo ColumnMetadata) error {
+	switch f.operator {
+	case opEq, opNe, opGt, opGte, opLt, opLte, opLike, opIn:
+	default:
+		return badRequestError(fmt.Sprintf(UnknownOperatorErr, f.operator))
+	}
+	if f.operator == opLike && colInfo.columnType.kind != KindString && colInfo.columnType.kind != KindSet {
+		// Only text is matched by pattern in every dialect: PostgreSQL has no LIKE for numbers, dates, enums.
+		return badRequestError(fmt.Sprintf(LikeNotTextErr, f.column))
+	}
+	f.args = make([]interface{}, len(f.values))
+	for i, value := range f.values {
+		if value == "" && f.operator != opEq && f.operator != opNe {
+			return badRequestError(fmt.Sprintf(EmptyFilterValueErr, f.column))
+		}
+		f.args[i] = value
+		if f.isNullCheck() || f.operator == opLike {
+			continue
+		}
+		converted, ok := filterValue(value, colInfo.columnType)
+		if !ok {
+			return badRequestError(fmt.Sprintf(InvalidIDTypeErrParrern, f.column))
+		}
+		f.args[i] = converted
+	}
+	return nil
+}
+
+// Convert filter value from query string into database value of column type.
+// Booleans are accepted as `true` / `false` / `1` / `0`, datetime may be compared with date.
+func filterValue(value string, columnType ColumnType) (interface{}, bool) {
+	switch {
+	case columnType.isNumeric():
+		return columnType.convert(json.Number(value))
+	case columnType.kind == KindBool, columnType.kind == KindBit:
+		switch value {
+		case "true", "1":
+			return columnType.convert(true)
+		case "false", "0":
+			return columnType.convert(false)
+		}
+		return nil, false
+	case columnType.kind == KindDateTime:
+		if _, err := time.Parse(dateLayout, value); err == nil {
+			return value, true
+		}
+	case columnType.kind == KindString, columnType.kind == KindJSON:
+		return value, true // Compared as text: any value is valid, longer ones just do not match.
+	}
+	return columnType.convert(value)
+}
+
+func (f Filter) isNullCheck() bool {
+	return (f.operator == opEq || f.operator == opNe) && f.values[0] == nullFilterValue
+}
+
+// SQL condition with placeholders and values bound to them.
+type Predicate struct {
+	sql  string
+	args []interface{}
+}
+
+// Render filter as SQL predicate with placeholders. Values are always bound, never inlined.
+func (f Filter) predicate() Predicate {
+	column := "`" + f.column + "`"
+	if f.isNullCheck() {
+		if f.operator == opNe {
+			return Predicate{sql: column + " IS NOT NULL"}
+		}
+		return Predicate{sql: column + " IS NULL"}
+	}
+	values := f.args
+	switch f.operator {
+	case opIn:
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+		return Predicate{sql: column + " IN (" + placeholders + ")", args: values}
+	case opNe:
+		return Predicate{sql: column + " <> ?", args: values}
+	case opGt:
+		return Predicate{sql: column + " > ?", args: values}
+	case opGte:
+		return Predicate{sql: column + " >= ?", args: values}
+	case opLt:
+		return Predicate{sql: column + " < ?", args: values}
+	case opLte:
+		return Predicate{sql: column + " <= ?", args: values}
+	case opLike:
+		return Predicate{sql: column + " LIKE ?", args: values}
+	default:
+		return Predicate{sql: column + " = ?", args: values}
+	}
+}
+
+// Convert filters to predicates to be joined in `WHERE` clause.
+func filterPredicates(filters []Filter) []Predicate {
+	predicates := make([]Predicate, len(filters))
+	for i, filter := range filters {
+		predicates[i] = filter.predicate()
+	}
+	return predicates
+}
+
+// Build `WHERE ...` clause joining all predicates with AND. Empty string if no predicates given.
+func buildWhereClause(predicates []Predicate) (string, []interface{}) {
+	if len(predicates) == 0 {
+		return "", nil
+	}
+	conditions := make([]string, len(predicates))
+	args := make([]interface{}, 0, len(predicates))
+	for i, predicate := range predicates {
+		conditions[i] = predicate.sql
+		args = append(args, predicate.args...)
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
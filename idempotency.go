@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyHeader     = "Idempotency-Key"
+	replayedHeader        = "Idempotent-Replayed" // Set on replayed responses.
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKey     = 255
+	anonymousScope        = "anonymous:"    // Keys of requests without principal: authentication is not configured.
+	principalScope        = "principal:%q:" // Keys of authenticated client, quoted id can not be confused with key.
+	// ------------------ idempotency table -----------------------
+	createIdempotencyTable = "CREATE TABLE IF NOT EXISTS `%s` (" +
+		"idempotency_key varchar(255) NOT NULL, fingerprint char(64) NOT NULL, " +
+		"status int NOT NULL DEFAULT 0, header text, body %s, completed smallint NOT NULL DEFAULT 0, " +
+		"expires_at bigint NOT NULL, PRIMARY KEY (idempotency_key))"
+	reserveIdempotencyKey  = "INSERT INTO `%s` (idempotency_key, fingerprint, expires_at) VALUES (?, ?, ?)"
+	selectIdempotencyKey   = "SELECT fingerprint, status, header, body, completed, expires_at FROM `%s` WHERE idempotency_key = ?"
+	completeIdempotencyKey = "UPDATE `%s` SET status = ?, header = ?, body = ?, completed = 1 WHERE idempotency_key = ?"
+	releaseIdempotencyKey  = "DELETE FROM `%s` WHERE idempotency_key = ?"
+	purgeIdempotencyKeys   = "DELETE FROM `%s` WHERE expires_at < ?"
+	// ------------------ errors ----------------------------------
+	IdempotencyKeyTooLongErr = "Idempotency-Key is longer than %d characters"
+	IdempotencyMismatchErr   = "Idempotency-Key was used for different request"
+	IdempotencyInProgressErr = "request with the same Idempotency-Key is in progress"
+	IdempotencyNoClientErr   = "Idempotency-Key requires client identified by principal id"
+)
+
+// Stored outcome of write request made with `Idempotency-Key`.
+type IdempotencyRecord struct {
+	Fingerprint string      // Hash of method, path, query and body of original request.
+	Status      int         // Http status of original response.
+	Header      http.Header // Headers of original response.
+	Body        []byte      // Body of original response.
+	Completed   bool        // Original request is finished, response can be replayed.
+}
+
+// Storage of idempotency records. Implementations have to be safe for concurrent use.
+type IdempotencyStore interface {
+	// Reserve key for request with fingerprint. Nil if key is new, stored record otherwise.
+	Reserve(key, fingerprint string) (*IdempotencyRecord, error)
+	// Save response of finished request.
+	Complete(key string, record IdempotencyRecord) error
+	// Forget key, so request can be retried: original request failed without result worth replaying.
+	Release(key string) error
+}
+
+// Keep idempotency records in custom store.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(d *DBExplorer) {
+		d.idempotency = store
+	}
+}
+
+// Keep idempotency records in table of the same database for `ttl`. Table is created if missing
+// and is not exposed by explorer.
+func WithIdempotencyTable(table string, ttl time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.idempotencyTable = table
+		d.idempotencyTTL = ttl
+	}
+}
+
+func (d *DBExplorer) initIdempotencyStore() error {
+	if d.idempotency != nil {
+		return nil
+	}
+	if d.idempotencyTable == "" {
+		d.idempotency = NewMemoryIdempotencyStore(d.idempotencyTTL)
+		return nil
+	}
+	store, err := NewTableIdempotencyStore(d.db, d.dialect, d.idempotencyTable, d.idempotencyTTL)
+	if err != nil {
+		return err
+	}
+	d.hiddenTables[d.idempotencyTable] = true
+	d.idempotency = store
+	return nil
+}
+
+// Serve write request with `Idempotency-Key`: first request is executed and its response stored,
+// repeats with the same key and request are replied with stored response.
+func (d *DBExplorer) serveIdempotent(w http.ResponseWriter, r *http.Request, key string) {
+	if len(key) > maxIdempotencyKey {
+		reply(w, Resp(nil, http.StatusBadRequest, fmt.Errorf(IdempotencyKeyTooLongErr, maxIdempotencyKey)))
+		return
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	closeResources(r.Body)
+	if err != nil {
+		reply(w, Resp(nil, http.StatusBadRequest, err))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rawBody)) // Body is read once again on routing.
+
+	// Keys of different clients never collide: stored response is replayed only to client which made request.
+	switch principal := PrincipalFromContext(r.Context()); {
+	case principal == nil:
+		key = anonymousScope + key
+	case principal.ID == "":
+		reply(w, Resp(nil, http.StatusBadRequest, errors.New(IdempotencyNoClientErr)))
+		return
+	default:
+		key = fmt.Sprintf(principalScope, principal.ID) + key
+	}
+	fingerprint := requestFingerprint(r, rawBody)
+	stored, err := d.idempotency.Reserve(key, fingerprint)
+	switch {
+	case err != nil:
+		reply(w, Resp(nil, http.StatusInternalServerError, err))
+		return
+	case stored == nil: // New request.
+	case stored.Fingerprint != fingerprint:
+		reply(w, Resp(nil, http.StatusUnprocessableEntity, errors.New(IdempotencyMismatchErr)))
+		return
+	case !stored.Completed:
+		reply(w, Resp(nil, http.StatusConflict, conflictError{message: IdempotencyInProgressErr, reason: CodeIdempotencyInProgress}))
+		return
+	default:
+		for name, values := range stored.Header {
+			if _, set := w.Header()[name]; !set { // Headers of this response are fresh: pin to primary, cookies.
+				w.Header()[name] = values
+			}
+		}
+		w.Header().Set(replayedHeader, "true")
+		safeWrite(w, stored.Status, stored.Body)
+		return
+	}
+
+	recorder := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	d.route(recorder, r)
+	if recorder.status >= http.StatusInternalServerError {
+		err = d.idempotency.Release(key)
+	} else {
+		err = d.idempotency.Complete(key, IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			Header:      w.Header().Clone(),
+			Body:        recorder.body.Bytes(),
+			Completed:   true,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to store response of idempotent request %s %s: %s", r.Method, r.URL.Path, err)
+	}
+}
+
+// Hash of request identity: the same key with different request is rejected.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Pass response to client keeping copy of status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(content []byte) (int, error) {
+	w.body.Write(content)
+	return w.ResponseWriter.Write(content)
+}
+
+// ----- in-memory store -----
+
+// Default store: records are kept in process memory and expire after ttl.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	records   map[string]memoryRecord
+	lastPurge time.Time
+}
+
+type memoryRecord struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, records: make(map[string]memoryRecord), lastPurge: time.Now()}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastPurge) > s.ttl {
+		for stored, entry := range s.records {
+			if now.After(entry.expires) {
+				delete(s.records, stored)
+			}
+		}
+		s.lastPurge = now
+	}
+	if entry, found := s.records[key]; found && now.Before(entry.expires) {
+		record := entry.record
+		return &record, nil
+	}
+	s.records[key] = memoryRecord{record: IdempotencyRecord{Fingerprint: fingerprint}, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryRecord{record: record, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// ----- table store -----
+
+// Records are kept in database table, so they are shared by all instances of service.
+type TableIdempotencyStore struct {
+	db    querier // Accepts canonical statements of dialect.
+	table string
+	ttl   time.Duration
+}
+
+// Create store over table, table is created if it does not exist.
+func NewTableIdempotencyStore(db *sql.DB, dialect Dialect, table string, ttl time.Duration) (*TableIdempotencyStore, error) {
+	store := &TableIdempotencyStore{db: boundQuerier{q: db, dialect: dialect}, table: table, ttl: ttl}
+	if _, err := store.exec(fmt.Sprintf(createIdempotencyTable, table, dialect.BinaryType())); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *TableIdempotencyStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(context.Background(), query, args...)
+}
+
+func (s *TableIdempotencyStore) Reserve(key, fingerprint string) (*IdempotencyRecord, error) {
+	now := time.Now()
+	if _, err := s.exec(fmt.Sprintf(purgeIdempotencyKeys, s.table), now.Unix()); err != nil {
+		return nil, err
+	}
+	_, insertErr := s.exec(fmt.Sprintf(reserveIdempotencyKey, s.table), key, fingerprint, now.Add(s.ttl).Unix())
+	if insertErr == nil {
+		return nil, nil
+	}
+	// Insert fails on duplicate key: reply with existing record.
+	var (
+		record    IdempotencyRecord
+		header    sql.NullString
+		completed bool
+		expires   int64
+	)
+	row := s.db.QueryRowContext(context.Background(), fmt.Sprintf(selectIdempotencyKey, s.table), key)
+	if err := row.Scan(&record.Fingerprint, &record.Status, &header, &record.Body, &completed, &expires); err != nil {
+		return nil, insertErr
+	}
+	record.Completed = completed
+	if header.Valid {
+		if err := json.Unmarshal([]byte(header.String), &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+func (s *TableIdempotencyStore) Complete(key string, record IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(fmt.Sprintf(completeIdempotencyKey, s.table), record.Status, string(header), record.Body, key)
+	return err
+}
+
+func (s *TableIdempotencyStore) Release(key string) error {
+	_, err := s.exec(fmt.Sprintf(releaseIdempotencyKey, s.table), key)
+	return err
+}
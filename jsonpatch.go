@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	pointerSeparator = "/"
+	appendIndex      = "-" // Index past the end of array in `add` operation.
+	// ------------------ errors ----------------------------------
+	InvalidPointerErr  = "invalid json pointer %q"
+	PathNotFoundErr    = "path %q not found"
+	InvalidPatchOpErr  = "invalid patch operation %d"
+	UnknownPatchOpErr  = "unknown patch operation %q"
+	PatchTestFailedErr = "test of path %q failed"
+	MoveIntoItselfErr  = "can not move %q into itself"
+)
+
+// Apply JSON Merge Patch (RFC 7396) to target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// Apply JSON Patch (RFC 6902) operations to document. Document is modified in place.
+func applyJSONPatch(document interface{}, patch interface{}) (interface{}, error) {
+	operations, ok := patch.([]interface{})
+	if !ok {
+		return nil, badRequestError(InvalidPatchErr)
+	}
+	for i, raw := range operations {
+		operation, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, badRequestError(fmt.Sprintf(InvalidPatchOpErr, i))
+		}
+		op, _ := operation["op"].(string)
+		path, ok := operation["path"].(string)
+		if !ok {
+			return nil, badRequestError(fmt.Sprintf(InvalidPatchOpErr, i))
+		}
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+		value, hasValue := operation["value"]
+		from, hasFrom := operation["from"].(string)
+		if ((op == "add" || op == "replace" || op == "test") && !hasValue) ||
+			((op == "move" || op == "copy") && !hasFrom) {
+			return nil, badRequestError(fmt.Sprintf(InvalidPatchOpErr, i))
+		}
+
+		switch op {
+		case "add":
+			document, err = addValue(document, tokens, value)
+		case "remove":
+			document, _, err = removeValue(document, tokens)
+		case "replace":
+			if document, _, err = removeValue(document, tokens); err == nil {
+				document, err = addValue(document, tokens, value)
+			}
+		case "move":
+			var fromTokens []string
+			if fromTokens, err = parsePointer(from); err != nil {
+				return nil, err
+			}
+			if from != path && strings.HasPrefix(path, from+pointerSeparator) {
+				return nil, badRequestError(fmt.Sprintf(MoveIntoItselfErr, from))
+			}
+			var moved interface{}
+			if document, moved, err = removeValue(document, fromTokens); err == nil {
+				document, err = addValue(document, tokens, moved)
+			}
+		case "copy":
+			var fromTokens []string
+			if fromTokens, err = parsePointer(from); err != nil {
+				return nil, err
+			}
+			var copied interface{}
+			if copied, err = getValue(document, fromTokens); err == nil {
+				document, err = addValue(document, tokens, deepCopy(copied))
+			}
+		case "test":
+			var actual interface{}
+			if actual, err = getValue(document, tokens); err == nil && !jsonEqual(actual, value) {
+				err = badRequestError(fmt.Sprintf(PatchTestFailedErr, path))
+			}
+		default:
+			err = badRequestError(fmt.Sprintf(UnknownPatchOpErr, op))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return document, nil
+}
+
+// Unescape JSON Pointer (RFC 6901) into reference tokens. Empty pointer refers to whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, pointerSeparator) {
+		return nil, badRequestError(fmt.Sprintf(InvalidPointerErr, pointer))
+	}
+	tokens := strings.Split(pointer[1:], pointerSeparator)
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getValue(document interface{}, tokens []string) (interface{}, error) {
+	current := document
+	for _, token := range tokens {
+		switch container := current.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+			}
+			current = container[index]
+		default:
+			return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+		}
+	}
+	return current, nil
+}
+
+// Add value at location: set object member or insert into array. Updated document is returned.
+func addValue(document interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil // Whole document is replaced.
+	}
+	return updateParent(document, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[key] = value
+			return container, nil
+		case []interface{}:
+			index := len(container)
+			if key != appendIndex {
+				var err error
+				if index, err = arrayIndex(key, len(container)); err != nil {
+					return nil, err
+				}
+			}
+			return append(container[:index], append([]interface{}{value}, container[index:]...)...), nil
+		}
+		return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+	})
+}
+
+// Remove value at location. Updated document and removed value are returned.
+func removeValue(document interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+	var removed interface{}
+	updated, err := updateParent(document, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			value, ok := container[key]
+			if !ok {
+				return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+			}
+			removed = value
+			delete(container, key)
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(key, len(container)-1)
+			if err != nil {
+				return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+			}
+			removed = container[index]
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+	})
+	return updated, removed, err
+}
+
+// Walk to container holding the last token and replace it with result of `change`.
+// Arrays may be reallocated, so every container on the way is reassigned to its parent.
+func updateParent(document interface{}, tokens []string,
+	change func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return change(document, tokens[0])
+	}
+	switch container := document.(type) {
+	case map[string]interface{}:
+		child, ok := container[tokens[0]]
+		if !ok {
+			return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+		}
+		updated, err := updateParent(child, tokens[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = updated
+		return container, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(container)-1)
+		if err != nil {
+			return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+		}
+		updated, err := updateParent(container[index], tokens[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	}
+	return nil, badRequestError(fmt.Sprintf(PathNotFoundErr, joinPointer(tokens)))
+}
+
+// Parse array index token: decimal without leading zeros not greater than `last`.
+func arrayIndex(token string, last int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > last || (len(token) > 1 && token[0] == '0') {
+		return 0, badRequestError(fmt.Sprintf(InvalidPointerErr, token))
+	}
+	return index, nil
+}
+
+func joinPointer(tokens []string) string {
+	escaped := make([]string, len(tokens))
+	for i, token := range tokens {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return pointerSeparator + strings.Join(escaped, pointerSeparator)
+}
+
+// Copy value decoded from json, so copies can be patched independently.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// Compare json values: numbers are equal by exact value (`1` and `1.0`, but not bigints beyond float precision),
+// objects regardless of member order.
+func jsonEqual(a, b interface{}) bool {
+	switch left := a.(type) {
+	case json.Number:
+		right, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		l, lOk := new(big.Rat).SetString(left.String())
+		r, rOk := new(big.Rat).SetString(right.String())
+		return lOk && rOk && l.Cmp(r) == 0
+	case map[string]interface{}:
+		right, ok := b.(map[string]interface{})
+		if !ok || len(left) != len(right) {
+			return false
+		}
+		for key, value := range left {
+			other, presented := right[key]
+			if !presented || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		right, ok := b.([]interface{})
+		if !ok || len(left) != len(right) {
+			return false
+		}
+		for i := range left {
+			if !jsonEqual(left[i], right[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -4,10 +4,17 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 var (
@@ -19,17 +26,63 @@ var (
 )
 
 func main() {
-	db, err := sql.Open("mysql", DSN)
-	err = db.Ping() // here will be the first connection to the database
+	config, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	logOutput := io.Writer(os.Stdout)
+	if config.Log.File != "" {
+		file, err := os.OpenFile(config.Log.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		logOutput = file
+		log.SetOutput(file)
 	}
+	fmt.Fprintf(logOutput, "effective config:\n%s", config)
 
-	handler, err := NewDbExplorer(db)
+	options, err := config.Options(logOutput)
 	if err != nil {
 		panic(err)
 	}
+	var handler http.Handler
+	if config.Databases != "" {
+		mounts, err := LoadMounts(config.Databases, options...)
+		if err != nil {
+			panic(err)
+		}
+		if handler, err = NewMultiExplorer(mounts...); err != nil {
+			panic(err)
+		}
+	} else {
+		db, err := sql.Open(config.Driver, config.DSN)
+		err = db.Ping() // here will be the first connection to the database
+		if err != nil {
+			panic(err)
+		}
+		if len(config.Replicas) > 0 {
+			replicaDBs := make([]*sql.DB, 0, len(config.Replicas))
+			for _, replicaDSN := range config.Replicas {
+				replicaDB, err := sql.Open(config.Driver, replicaDSN)
+				if err != nil {
+					panic(err)
+				}
+				replicaDBs = append(replicaDBs, replicaDB)
+			}
+			options = append(options, WithReplicas(replicaDBs...), WithReadYourWrites(config.ReadYourWrites))
+		}
+		if handler, err = NewDbExplorer(db, options...); err != nil {
+			panic(err)
+		}
+	}
 
-	fmt.Println("starting server at :8082")
-	http.ListenAndServe(":8082", handler)
+	fmt.Fprintf(logOutput, "starting server at %s\n", config.Listen)
+	if err := http.ListenAndServe(config.Listen, handler); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -4,28 +4,76 @@ import "strings"
 
 // Non-exported struct to collect required information about database column.
 type ColumnMetadata struct {
-	fieldName       string // Name of column
-	isNumericType   bool   // Is it a numeric type
+	fieldName       string     // Name of column
+	columnType      ColumnType // Parsed type of column.
 	isNullable      bool
 	isAutoIncrement bool
+	isPrimaryKey    bool   // Column is part of primary key.
+	isUnique        bool   // Column alone is unique: single column unique index, primary key aside.
+	hasDefault      bool   // Column has default value declared in schema.
+	defaultExpr     string // Declared default expression, SQLite only: its UPDATE does not accept DEFAULT.
+	mask            Mask   // Replace value in replies. Nil if column is not masked.
 }
 
 type TableMetadata struct {
 	columnsInfo []ColumnMetadata
 	columnNames []string                  // Easier way to iterate column names.
 	hash        map[string]ColumnMetadata // Faster way to obtain column info by it's name.
+	primaryKey  []string                  // Columns identifying row: `/$table/1` or `/$table/1,42` for composite key.
 }
 
 // Internal function to build Column Info based on attributes from database.
-func newColumnInfo(fieldName, fType, extra, null string) ColumnMetadata {
+func newColumnInfo(fieldName, fType, extra, null, key string, hasDefault bool) ColumnMetadata {
 	return ColumnMetadata{
 		fieldName:       fieldName,                            // Name of column.
-		isNumericType:   strings.Contains(fType, "int"),       // Column type [ numeric / text].
+		columnType:      parseColumnType(fType),               // Column type: decimal(10,2), enum('a','b')...
 		isNullable:      null == "YES",                        // Nullability of column.
-		isAutoIncrement: strings.Contains(extra, "increment")} // Is column auto-incremental.
+		isAutoIncrement: strings.Contains(extra, "increment"), // Is column auto-incremental.
+		isPrimaryKey:    key == "PRI",                         // Is column part of primary key.
+		isUnique:        key == "UNI",                         // Is column unique by itself.
+		hasDefault:      hasDefault}                           // Is default value declared.
 }
 
 // Obtain column's info by it's name.
 func (t TableMetadata) getColumn(name string) ColumnMetadata {
 	return t.hash[name]
 }
+
+// Resolve columns identifying row: primary key columns in order of table columns.
+// Auto-incremental column is used for tables without primary key.
+func findPrimaryKey(columnsInfo []ColumnMetadata) []string {
+	primaryKey := make([]string, 0, 1)
+	for i := 0; i < len(columnsInfo); i++ {
+		if columnsInfo[i].isPrimaryKey {
+			primaryKey = append(primaryKey, columnsInfo[i].fieldName)
+		}
+	}
+	if len(primaryKey) > 0 {
+		return primaryKey
+	}
+	for i := 0; i < len(columnsInfo); i++ {
+		if columnsInfo[i].isAutoIncrement {
+			return []string{columnsInfo[i].fieldName}
+		}
+	}
+	return nil // No candidate for id found among columns.
+}
+
+// Column generated by database on insert. Empty string if there is no such column.
+func (t TableMetadata) autoIncrementColumn() string {
+	for i := 0; i < len(t.columnsInfo); i++ {
+		if t.columnsInfo[i].isAutoIncrement {
+			return t.columnsInfo[i].fieldName
+		}
+	}
+	return ""
+}
+
+func (t TableMetadata) isPrimaryKeyColumn(name string) bool {
+	for _, column := range t.primaryKey {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
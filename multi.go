@@ -0,0 +1,209 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	databasesPath      = "/db" // Listing of mounted databases, `/db/$name/...` is served by explorer of database.
+	defaultDriver      = "mysql"
+	UnknownDatabaseErr = "unknown database"
+	InvalidMountErr    = "invalid database name %q"
+	DuplicateMountErr  = "database %s is mounted twice"
+)
+
+// Database served under `/db/$name`. Options apply to this database only: visibility, permissions, pool...
+type Mount struct {
+	Name    string
+	DB      *sql.DB
+	Options []Option
+}
+
+// Several databases behind one handler. `/db/$name/$table/$id` is served by explorer of database `$name`
+// the same way single explorer serves `/$table/$id`. `GET /db` lists mounted databases.
+type MultiExplorer struct {
+	names     []string // Mounted databases in order of mounting.
+	explorers map[string]*DBExplorer
+}
+
+// Create explorer of every database. Databases are not shared: each one has its own metadata, pool and permissions.
+func NewMultiExplorer(mounts ...Mount) (*MultiExplorer, error) {
+	m := &MultiExplorer{
+		names:     make([]string, 0, len(mounts)),
+		explorers: make(map[string]*DBExplorer, len(mounts)),
+	}
+	for _, mount := range mounts {
+		if mount.Name == "" || strings.ContainsAny(mount.Name, "/?#") {
+			m.Close()
+			return nil, fmt.Errorf(InvalidMountErr, mount.Name)
+		}
+		if _, mounted := m.explorers[mount.Name]; mounted {
+			m.Close()
+			return nil, fmt.Errorf(DuplicateMountErr, mount.Name)
+		}
+		explorer, err := NewDbExplorer(mount.DB, mount.Options...)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("database %s: %w", mount.Name, err)
+		}
+		explorer.basePath = databasesPath + "/" + url.PathEscape(mount.Name)
+		m.names = append(m.names, mount.Name)
+		m.explorers[mount.Name] = explorer
+	}
+	return m, nil
+}
+
+// Names of mounted databases.
+func (m *MultiExplorer) ListDatabases() []string {
+	return m.names
+}
+
+// Explorer of mounted database. Nil if database is not mounted.
+func (m *MultiExplorer) Explorer(name string) *DBExplorer {
+	return m.explorers[name]
+}
+
+// Stop background work of all explorers. Database handles are left open.
+func (m *MultiExplorer) Close() error {
+	for _, explorer := range m.explorers {
+		explorer.Close()
+	}
+	return nil
+}
+
+// Serve `GET /db` or pass request to explorer of database with `/db/$name` prefix stripped from path.
+func (m *MultiExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == databasesPath || r.URL.Path == databasesPath+"/" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			reply(w, Resp(nil, http.StatusMethodNotAllowed, errors.New(MethodNotAllowedErr)))
+			return
+		}
+		m.listDatabases(w, r)
+		return
+	}
+	// Escaped path is stripped: explorer tells `%2C` in id from separator by it.
+	mountPath, mounted := strings.CutPrefix(r.URL.EscapedPath(), databasesPath+"/")
+	if !mounted {
+		reply(w, Resp(nil, http.StatusNotFound, notFoundError(NoEndpointErr)))
+		return
+	}
+	escapedName, rest, _ := strings.Cut(mountPath, "/")
+	name, err := url.PathUnescape(escapedName)
+	if err != nil {
+		reply(w, Resp(nil, http.StatusBadRequest, err))
+		return
+	}
+	explorer, known := m.explorers[name]
+	if !known {
+		reply(w, Resp(nil, http.StatusNotFound, unknownDatabaseError(UnknownDatabaseErr)))
+		return
+	}
+	stripped := new(http.Request)
+	*stripped = *r
+	stripped.URL = new(url.URL)
+	*stripped.URL = *r.URL
+	stripped.URL.RawPath = "/" + rest
+	if stripped.URL.Path, err = url.PathUnescape(stripped.URL.RawPath); err != nil {
+		reply(w, Resp(nil, http.StatusBadRequest, err))
+		return
+	}
+	explorer.ServeHTTP(w, stripped)
+}
+
+// List databases client is authenticated by. Unauthorized if none of them accepts credentials.
+func (m *MultiExplorer) listDatabases(w http.ResponseWriter, r *http.Request) {
+	databases := make([]string, 0, len(m.names))
+	var failure error
+	for _, name := range m.names {
+		if _, err := m.explorers[name].authenticate(r); err != nil {
+			failure = err
+			continue
+		}
+		databases = append(databases, name)
+	}
+	if len(databases) == 0 && failure != nil {
+		w.Header().Set("WWW-Authenticate", authenticateHeader)
+		reply(w, Resp(nil, http.StatusUnauthorized, failure))
+		return
+	}
+	reply(w, Resp(map[string][]string{"databases": databases}, http.StatusOK, nil))
+}
+
+// Database of mounts file: `{"billing": {"driver": "postgres", "dsn": "postgres://...", "read_only": true}}`.
+type MountConfig struct {
+	Driver       string   `json:"driver"` // mysql by default.
+	DSN          string   `json:"dsn"`
+	Tables       []string `json:"tables"` // Exposed tables, all if empty.
+	ReadOnly     bool     `json:"read_only"`
+	Policy       string   `json:"policy"` // Policy file of database replacing shared one.
+	MaxOpenConns int      `json:"max_open_conns"`
+	MaxIdleConns int      `json:"max_idle_conns"`
+}
+
+// Open databases listed in json file, mounted in order of names. Shared options (authentication, timeouts...)
+// are applied to every database before its own settings.
+func LoadMounts(path string, shared ...Option) ([]Mount, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]MountConfig)
+	if err := json.Unmarshal(raw, &configs); err != nil {
+		return nil, fmt.Errorf("invalid databases file %s: %w", path, err)
+	}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	mounts := make([]Mount, 0, len(names))
+	for _, name := range names {
+		mount, err := openMount(name, configs[name], shared)
+		if err != nil {
+			for _, opened := range mounts {
+				opened.DB.Close()
+			}
+			return nil, fmt.Errorf("database %s: %w", name, err)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+func openMount(name string, config MountConfig, shared []Option) (Mount, error) {
+	options := slices.Clone(shared)
+	if len(config.Tables) > 0 {
+		options = append(options, WithTables(config.Tables...))
+	}
+	if config.ReadOnly {
+		options = append(options, WithReadOnly())
+	}
+	if config.Policy != "" {
+		policy, err := LoadPolicy(config.Policy)
+		if err != nil {
+			return Mount{}, err
+		}
+		options = append(options, WithPolicy(policy))
+	}
+	if config.MaxOpenConns != 0 || config.MaxIdleConns != 0 {
+		options = append(options, WithPool(PoolConfig{MaxOpenConns: config.MaxOpenConns, MaxIdleConns: config.MaxIdleConns}))
+	}
+	driver := config.Driver
+	if driver == "" {
+		driver = defaultDriver
+	}
+	db, err := sql.Open(driver, config.DSN)
+	if err != nil {
+		return Mount{}, err
+	}
+	return Mount{Name: name, DB: db, Options: options}, nil
+}
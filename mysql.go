@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	showTablesQuery  = "SHOW TABLES"
+	showColumnsQuery = "SHOW FULL COLUMNS FROM `%s`"
+	mysqlSchemaQuery = "SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA " +
+		"FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION"
+	mysqlEstimatedCount = "SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+)
+
+// MySQL and compatible databases: MariaDB, TiDB...
+type MySQLDialect struct{}
+
+func (MySQLDialect) Name() string { return "mysql" }
+
+func (MySQLDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (MySQLDialect) Placeholder(int) string { return "?" }
+
+func (MySQLDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, showTablesQuery)
+}
+
+// Columns as reported by `SHOW FULL COLUMNS`.
+func (MySQLDialect) Columns(ctx context.Context, q querier, table string) ([]ColumnMetadata, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(showColumnsQuery, table))
+	if err != nil {
+		return nil, err
+	}
+	defer closeResources(rows)
+	columnsInfo := []ColumnMetadata{}
+	for rows.Next() {
+		var (
+			field, tType, tNull, key, tExtra, privileges, comment string
+			collation, tDefault                                   interface{}
+		)
+		err := rows.Scan(&field, &tType, &collation, &tNull, &key, &tDefault, &tExtra, &privileges, &comment)
+		if err != nil {
+			return nil, err
+		}
+		columnsInfo = append(columnsInfo, newColumnInfo(field, tType, tExtra, tNull, key, tDefault != nil))
+	}
+	return columnsInfo, rows.Err()
+}
+
+func (MySQLDialect) SchemaVersionQuery() string { return mysqlSchemaQuery }
+
+func (MySQLDialect) NullsOrder(bool) string { return "" }
+
+func (MySQLDialect) EstimatedCountQuery(table string) (string, []interface{}) {
+	return mysqlEstimatedCount, []interface{}{table}
+}
+
+func (MySQLDialect) LockClause() string { return lockClause }
+
+func (MySQLDialect) DefaultValue(ColumnMetadata) string { return "DEFAULT" }
+
+func (MySQLDialect) DefaultValues() string { return "() VALUES ()" }
+
+func (MySQLDialect) Returning() bool { return false }
+
+func (MySQLDialect) BinaryType() string { return "longblob" }
+
+// Read first column of every row as string.
+func queryStrings(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResources(rows)
+	values := make([]string, 0, 10)
+	var value string
+	for rows.Next() {
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
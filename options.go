@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Optional setting of DBExplorer, passed to NewDbExplorer.
+type Option func(*DBExplorer)
+
+// Limits of connection pool set by NewDbExplorer on database handles. Zero fields keep settings of handle,
+// negative ones remove the limit (no idle connections for MaxIdleConns) as `database/sql` does.
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+}
+
+// Set limits of pool on database handle.
+func (p PoolConfig) apply(db *sql.DB) {
+	if p.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+	if p.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+}
+
+// Limit connection pools of database and its replicas. Pool settings of handles are kept by default.
+func WithPool(pool PoolConfig) Option {
+	return func(d *DBExplorer) {
+		d.pool = pool
+	}
+}
+
+// Page size of listing without `limit` param (5 by default) and the largest accepted one.
+// Larger limits are reduced to `maxLimit`, no upper bound if it is zero.
+func WithLimits(defaultLimit, maxLimit int) Option {
+	return func(d *DBExplorer) {
+		d.defaultLimit, d.maxLimit = defaultLimit, maxLimit
+	}
+}
+
+// Write request tracking lines to `w` instead of stdout. `io.Discard` disables tracking.
+func WithRequestLog(w io.Writer) Option {
+	return func(d *DBExplorer) {
+		d.requestLog = w
+	}
+}
+
+// Disable all modifications: only GET requests are served, anything else is replied with 405.
+func WithReadOnly() Option {
+	return func(d *DBExplorer) {
+		d.readOnly = true
+	}
+}
+
+// Restrict http methods permitted for table. Tables without explicit config permit all methods.
+func WithTableMethods(table string, methods ...string) Option {
+	return func(d *DBExplorer) {
+		permitted := make([]string, len(methods))
+		for i, method := range methods {
+			permitted[i] = strings.ToUpper(method)
+		}
+		d.tableMethods[table] = permitted
+	}
+}
+
+// Methods served by explorer in order they are advertised in `Allow` header.
+func supportedMethods() []string {
+	return []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+}
+
+// Resolve methods permitted for table according to read-only mode and table config.
+func (d *DBExplorer) allowedMethods(table string) []string {
+	if table == "" || d.readOnly {
+		return []string{http.MethodGet}
+	}
+	permitted, configured := d.tableMethods[table]
+	if !configured {
+		return supportedMethods()
+	}
+	allowed := make([]string, 0, len(permitted))
+	for _, method := range supportedMethods() {
+		for _, candidate := range permitted {
+			if candidate == method {
+				allowed = append(allowed, method)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+func isMethodAllowed(method string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if candidate == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	orderParam        = "order" // Query parameter to sort records: `?order=-updated,id`
+	orderSeparator    = ","     // Separator of sort columns.
+	descendingPrefix  = "-"     // Prefix of column to sort in descending order.
+	InvalidOrderErr   = "invalid order %s"
+	DuplicateOrderErr = "duplicate order column %s"
+)
+
+// Single sort column requested by client.
+type OrderBy struct {
+	column     string
+	descending bool
+}
+
+// Parse `order` parameter against table columns. Primary key is always appended
+// (if not requested explicitly) to keep paging deterministic.
+func parseOrder(raw string, tableMetadata TableMetadata) ([]OrderBy, error) {
+	orders := make([]OrderBy, 0, 2)
+	seen := make(map[string]bool, 2)
+	if raw != "" {
+		for _, token := range strings.Split(raw, orderSeparator) {
+			order := OrderBy{column: strings.TrimPrefix(token, descendingPrefix)}
+			order.descending = len(order.column) < len(token)
+			if order.column == "" {
+				return nil, badRequestError(fmt.Sprintf(InvalidOrderErr, raw))
+			}
+			colInfo, known := tableMetadata.hash[order.column]
+			if !known {
+				return nil, badRequestError(fmt.Sprintf(UnknownColumnErr, order.column))
+			}
+			if colInfo.mask != nil {
+				return nil, badRequestError(fmt.Sprintf(MaskedColumnErr, order.column))
+			}
+			if seen[order.column] {
+				return nil, badRequestError(fmt.Sprintf(DuplicateOrderErr, order.column))
+			}
+			seen[order.column] = true
+			orders = append(orders, order)
+		}
+	}
+	for _, column := range tableMetadata.primaryKey {
+		if !seen[column] {
+			orders = append(orders, OrderBy{column: column})
+		}
+	}
+	return orders, nil
+}
+
+// Build ` ORDER BY ...` clause. Empty string if nothing to sort by.
+// NULL is sorted as the lowest value whatever database does by default: cursors rely on it.
+func buildOrderClause(orders []OrderBy, dialect Dialect) string {
+	if len(orders) == 0 {
+		return ""
+	}
+	columns := make([]string, len(orders))
+	for i, order := range orders {
+		columns[i] = "`" + order.column + "`"
+		if order.descending {
+			columns[i] += " DESC"
+		}
+		columns[i] += dialect.NullsOrder(order.descending)
+	}
+	return " ORDER BY " + strings.Join(columns, ", ")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	countParam      = "count"     // Query parameter to request total number of records: `?count=exact`
+	countExact      = "exact"     // Count matching records with `SELECT COUNT(*)`.
+	countEstimated  = "estimated" // Take table size estimation from database catalog. Filters are not taken into account.
+	InvalidCountErr = "invalid count %s"
+	// ------------------ DB QUERIES ------------------------------
+	countQuery = "SELECT COUNT(*) FROM `%s`%s"
+)
+
+// Page of table records along with details to navigate to neighbour pages.
+type Page struct {
+	content    map[string]interface{} // Reply to client: records and optional pagination metadata.
+	params     url.Values             // Query parameters page was requested with.
+	limit      int
+	offset     int
+	hasMore    bool
+	nextCursor string // Cursor of the next page. Empty unless keyset pagination is used.
+	useCursor  bool
+}
+
+// Validate `count` parameter. Empty string if total is not requested.
+func parseCount(raw string) (string, error) {
+	switch raw {
+	case "", countExact, countEstimated:
+		return raw, nil
+	}
+	return "", badRequestError(fmt.Sprintf(InvalidCountErr, raw))
+}
+
+// RFC 5988 `Link` header value with next/prev pages of table served under `basePath`.
+// Empty string if there are no neighbours.
+func (p *Page) links(basePath, table string) string {
+	path := basePath + "/" + url.PathEscape(table)
+	links := make([]string, 0, 2)
+	if p.hasMore {
+		next := cloneParams(p.params)
+		if p.useCursor {
+			next.Set(cursorParam, p.nextCursor)
+		} else {
+			next.Set("offset", strconv.Itoa(p.offset+p.limit))
+		}
+		links = append(links, formatLink(path, next, "next"))
+	}
+	if !p.useCursor && p.offset > 0 {
+		prev := cloneParams(p.params)
+		prev.Set("offset", strconv.Itoa(max(p.offset-p.limit, 0)))
+		links = append(links, formatLink(path, prev, "prev"))
+	}
+	return strings.Join(links, ", ")
+}
+
+func formatLink(path string, params url.Values, rel string) string {
+	return fmt.Sprintf("<%s?%s>; rel=\"%s\"", path, params.Encode(), rel)
+}
+
+func cloneParams(params url.Values) url.Values {
+	clone := make(url.Values, len(params)+1)
+	for key, values := range params {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
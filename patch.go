@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	jsonMediaType  = "application/json"
+	mergePatchType = "application/merge-patch+json" // RFC 7396
+	jsonPatchType  = "application/json-patch+json"  // RFC 6902
+	lockClause     = " FOR UPDATE"                  // Keep patched row locked until update.
+	// ------------------ errors ----------------------------------
+	UnsupportedPatchErr = "unsupported patch media type %s: expected application/merge-patch+json or application/json-patch+json"
+	InvalidPatchErr     = "invalid patch"
+	PatchedNotObjectErr = "patched record is not an object"
+	RequiredColumnErr   = "field %s is required"
+	IDMismatchErr       = "field %s does not match id"
+)
+
+// Body of PATCH request: merge patch (`application/merge-patch+json`, `application/json`)
+// or list of operations (`application/json-patch+json`).
+type Patch struct {
+	mediaType string
+	document  interface{} // Decoded body, numbers as json.Number. Nil if body is not valid json.
+}
+
+// Read body of PATCH request. Nil for other methods.
+func extractPatch(r *http.Request) *Patch {
+	if r.Method != http.MethodPatch {
+		return nil
+	}
+	patch := &Patch{mediaType: mergePatchType}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		patch.mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	rawBodyBytes, err := io.ReadAll(r.Body)
+	defer closeResources(r.Body)
+	if err != nil {
+		return patch
+	}
+	decoder := json.NewDecoder(bytes.NewReader(rawBodyBytes))
+	decoder.UseNumber()
+	_ = decoder.Decode(&patch.document)
+	return patch
+}
+
+func (p *Patch) isSupported() bool {
+	switch p.mediaType {
+	case mergePatchType, jsonMediaType, jsonPatchType:
+		return true
+	}
+	return false
+}
+
+// Apply patch to json representation of record.
+func (p *Patch) apply(record interface{}) (interface{}, error) {
+	if p.mediaType == jsonPatchType {
+		return applyJSONPatch(record, p.document)
+	}
+	if _, ok := p.document.(map[string]interface{}); !ok {
+		return nil, badRequestError(InvalidPatchErr)
+	}
+	return mergePatch(record, p.document), nil
+}
+
+func (d *DBExplorer) handlePatch(w http.ResponseWriter, requestedData *Req) {
+	if !requestedData.patch.isSupported() {
+		err := fmt.Errorf(UnsupportedPatchErr, requestedData.patch.mediaType)
+		reply(w, Resp(nil, http.StatusUnsupportedMediaType, err))
+		return
+	}
+	result, err := d.inTransaction(requestedData.ctx, func(tx querier) (interface{}, error) {
+		if err := d.checkPrecondition(tx, requestedData); err != nil {
+			return nil, err
+		}
+		return d.patch(tx, requestedData)
+	})
+	if err != nil {
+		reply(w, d.failure(requestedData, errorStatus(err), err))
+		return
+	}
+	reply(w, Resp(result, http.StatusOK, nil))
+}
+
+// Apply patch to row addressed by id: current row is read and locked, patch is applied to its
+// json representation and changed columns are updated with the same rules as POST.
+func (d *DBExplorer) patch(q querier, req *Req) (interface{}, error) {
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	if req.patch == nil || !req.patch.isSupported() {
+		return nil, badRequestError(InvalidPatchErr)
+	}
+	entry, err := d.selectRow(q, req, true)
+	if err != nil {
+		return nil, err
+	}
+	// Go through json to patch the same representation as client gets.
+	encoded, err := json.Marshal(maskEntry(entry, tableMetadata.columnsInfo))
+	if err != nil {
+		return nil, err
+	}
+	var current, original map[string]interface{} // Patch modifies nested values in place, keep separate copy.
+	for _, target := range []*map[string]interface{}{&current, &original} {
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		if err := decoder.Decode(target); err != nil {
+			return nil, err
+		}
+	}
+	patched, err := req.patch.apply(current)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, badRequestError(PatchedNotObjectErr)
+	}
+
+	// Only changed columns are updated: masked values are kept unless replaced by client.
+	entity := make(DBEntry, len(record))
+	for _, column := range tableMetadata.columnNames {
+		if value := record[column]; !jsonEqual(value, original[column]) {
+			entity[column] = value // Removed member is stored as NULL.
+		}
+	}
+	if len(entity) == 0 {
+		return map[string]interface{}{"updated": 0}, nil
+	}
+	patchedReq := *req
+	patchedReq.body = entity
+	return d.update(q, &patchedReq)
+}
+
+// Replace row addressed by id with entry from request body: omitted nullable columns become NULL,
+// columns with declared default are reset to default, other omitted columns are rejected.
+func (d *DBExplorer) replace(q querier, req *Req) (interface{}, error) {
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	if len(req.id) != len(tableMetadata.primaryKey) {
+		return nil, badRequestError(InvalidIDErr)
+	}
+	if req.body == nil {
+		return nil, badRequestError(InvalidBodyErr) // Replacement must be explicit: nothing is reset on invalid body.
+	}
+	entity := make(DBEntry, len(req.body))
+	for column, value := range req.body {
+		entity[column] = value
+	}
+	// Primary key may be submitted as part of full representation of row, but can not be changed.
+	for i, column := range tableMetadata.primaryKey {
+		if value, presented := entity[column]; presented {
+			if fmt.Sprint(value) != req.id[i] {
+				return nil, newValidationError(fmt.Sprintf(IDMismatchErr, column), column, reasonIDMismatch)
+			}
+			delete(entity, column)
+		}
+	}
+	kept, err := d.keepMasked(q, req, entity)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.enforceRowFilters(req, entity, true); err != nil {
+		return nil, err
+	}
+	defaults := make([]string, 0, 2)
+	for _, column := range tableMetadata.columnsInfo {
+		if _, presented := entity[column.fieldName]; presented || kept[column.fieldName] ||
+			column.isAutoIncrement || tableMetadata.isPrimaryKeyColumn(column.fieldName) {
+			continue
+		}
+		switch {
+		case column.isNullable:
+			entity[column.fieldName] = nil
+		case column.hasDefault:
+			defaults = append(defaults, column.fieldName)
+		default:
+			message := fmt.Sprintf(RequiredColumnErr, column.fieldName)
+			return nil, newValidationError(message, column.fieldName, reasonRequired)
+		}
+	}
+	if err := validate(entity, tableMetadata.columnsInfo); err != nil {
+		return nil, err
+	}
+	if len(entity) == 0 && len(defaults) == 0 && len(kept) > 0 {
+		return map[string]interface{}{"updated": 0}, nil // Row is replaced with its own representation.
+	}
+	return d.updateRow(q, req, entity, defaults)
+}
+
+// Drop masked columns submitted with the same value client got in reply: representation read with GET
+// is written back unchanged, mask must not overwrite actual value. Reply with columns which are kept as is.
+func (d *DBExplorer) keepMasked(q querier, req *Req, entity DBEntry) (map[string]bool, error) {
+	tableMetadata := req.schema.metadata[req.table]
+	masked := false
+	for column := range entity {
+		masked = masked || tableMetadata.getColumn(column).mask != nil
+	}
+	if !masked {
+		return nil, nil
+	}
+	record, err := d.selectRow(q, req, true)
+	if err != nil {
+		if errors.As(err, new(notFoundError)) {
+			return nil, nil // Nothing to keep: update of missing row affects nothing.
+		}
+		return nil, err
+	}
+	// Compare in json representation as client sees it.
+	encoded, err := json.Marshal(maskEntry(record, tableMetadata.columnsInfo))
+	if err != nil {
+		return nil, err
+	}
+	var current map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&current); err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool, 1)
+	for column, value := range entity {
+		if tableMetadata.getColumn(column).mask != nil && jsonEqual(value, current[column]) {
+			delete(entity, column)
+			kept[column] = true
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+const (
+	pgTablesQuery = "SELECT table_name FROM information_schema.tables " +
+		"WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	// Enum types are reported as `enum('a','b')` to be validated the same way as MySQL enums.
+	pgColumnsQuery = "SELECT a.attname, " +
+		"CASE WHEN t.typtype = 'e' THEN 'enum(' || (SELECT string_agg(quote_literal(e.enumlabel), ',' ORDER BY e.enumsortorder) " +
+		"FROM pg_enum e WHERE e.enumtypid = t.oid) || ')' ELSE format_type(a.atttypid, a.atttypmod) END, " +
+		"NOT a.attnotnull, d.adbin IS NOT NULL, " +
+		"a.attidentity <> '' OR COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%', " +
+		"EXISTS (SELECT 1 FROM pg_index i WHERE i.indrelid = a.attrelid AND i.indisprimary AND a.attnum = ANY(i.indkey)), " +
+		"EXISTS (SELECT 1 FROM pg_index i WHERE i.indrelid = a.attrelid AND i.indisunique AND i.indnatts = 1 " +
+		"AND i.indpred IS NULL AND i.indkey[0] = a.attnum), " +
+		"EXISTS (SELECT 1 FROM pg_index i WHERE i.indrelid = a.attrelid AND a.attnum = ANY(i.indkey)) " +
+		"FROM pg_attribute a JOIN pg_type t ON t.oid = a.atttypid " +
+		"LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum " +
+		"WHERE a.attrelid = to_regclass(quote_ident(?)) AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum"
+	pgSchemaQuery = "SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position " +
+		"FROM information_schema.columns WHERE table_schema = current_schema() ORDER BY table_name, ordinal_position"
+	pgEstimatedCount = "SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = to_regclass(quote_ident(?))"
+)
+
+// PostgreSQL over `lib/pq` or `pgx` driver. Tables of current schema (first one of `search_path`) are exposed.
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string { return "postgres" }
+
+func (PostgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (PostgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (PostgresDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, pgTablesQuery)
+}
+
+// Columns from `pg_catalog`. Types are reported by `format_type`: `character varying(255)`, `numeric(10,2)`...
+func (PostgresDialect) Columns(ctx context.Context, q querier, table string) ([]ColumnMetadata, error) {
+	rows, err := q.QueryContext(ctx, pgColumnsQuery, table)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResources(rows)
+	columnsInfo := []ColumnMetadata{}
+	for rows.Next() {
+		var (
+			field, tType                                              string
+			nullable, hasDefault, generated, primary, unique, indexed bool
+		)
+		if err := rows.Scan(&field, &tType, &nullable, &hasDefault, &generated, &primary, &unique, &indexed); err != nil {
+			return nil, err
+		}
+		null, key, extra := "NO", "", ""
+		if nullable {
+			null = "YES"
+		}
+		switch {
+		case primary:
+			key = "PRI"
+		case unique:
+			key = "UNI"
+		case indexed:
+			key = "MUL"
+		}
+		if generated {
+			extra = "auto_increment"
+		}
+		columnsInfo = append(columnsInfo, newColumnInfo(field, tType, extra, null, key, hasDefault))
+	}
+	return columnsInfo, rows.Err()
+}
+
+func (PostgresDialect) SchemaVersionQuery() string { return pgSchemaQuery }
+
+// NULL is the highest value in PostgreSQL by default.
+func (PostgresDialect) NullsOrder(descending bool) string {
+	if descending {
+		return " NULLS LAST"
+	}
+	return " NULLS FIRST"
+}
+
+func (PostgresDialect) EstimatedCountQuery(table string) (string, []interface{}) {
+	return pgEstimatedCount, []interface{}{table}
+}
+
+func (PostgresDialect) LockClause() string { return lockClause }
+
+func (PostgresDialect) DefaultValue(ColumnMetadata) string { return "DEFAULT" }
+
+func (PostgresDialect) DefaultValues() string { return " DEFAULT VALUES" }
+
+func (PostgresDialect) Returning() bool { return true }
+
+func (PostgresDialect) BinaryType() string { return "bytea" }
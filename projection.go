@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	fieldsParam       = "fields" // Query parameter to select subset of columns: `?fields=user_id,login`
+	fieldsSeparator   = ","      // Separator of projected columns.
+	InvalidFieldsErr  = "invalid fields %s"
+	DuplicateFieldErr = "duplicate field %s"
+)
+
+// Resolve columns to be selected and returned to client. All table columns when `fields` is not requested.
+func parseFields(raw string, tableMetadata TableMetadata) ([]ColumnMetadata, error) {
+	if raw == "" {
+		return tableMetadata.columnsInfo, nil
+	}
+	names := strings.Split(raw, fieldsSeparator)
+	columns := make([]ColumnMetadata, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, badRequestError(fmt.Sprintf(InvalidFieldsErr, raw))
+		}
+		colInfo, known := tableMetadata.hash[name]
+		if !known {
+			return nil, badRequestError(fmt.Sprintf(UnknownColumnErr, name))
+		}
+		if seen[name] {
+			return nil, badRequestError(fmt.Sprintf(DuplicateFieldErr, name))
+		}
+		seen[name] = true
+		columns = append(columns, colInfo)
+	}
+	return columns, nil
+}
+
+// Comma separated list of quoted column names for `select` statement.
+func listColumns(columns []ColumnMetadata) string {
+	quoted := make([]string, len(columns))
+	for i := 0; i < len(columns); i++ {
+		quoted[i] = "`" + columns[i].fieldName + "`"
+	}
+	return strings.Join(quoted, ",")
+}
+
+// Extend projection with sort columns which were not requested by client.
+func withOrderColumns(columns []ColumnMetadata, orders []OrderBy, tableMetadata TableMetadata) []ColumnMetadata {
+	selected := append(make([]ColumnMetadata, 0, len(columns)+len(orders)), columns...)
+	for _, order := range orders {
+		if !containsColumn(selected, order.column) {
+			selected = append(selected, tableMetadata.getColumn(order.column))
+		}
+	}
+	return selected
+}
+
+// Remove from entries attributes which are not part of projection.
+func stripColumns(entries DBEntries, columns []ColumnMetadata) {
+	for _, entry := range entries {
+		for name := range entry {
+			if !containsColumn(columns, name) {
+				delete(entry, name)
+			}
+		}
+	}
+}
+
+func containsColumn(columns []ColumnMetadata, name string) bool {
+	for i := 0; i < len(columns); i++ {
+		if columns[i].fieldName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHealthInterval = 5 * time.Second
+	healthCheckTimeout    = time.Second
+	// Deadline of pin to primary set on response to write: unix time in milliseconds and its signature.
+	primaryUntilCookie = "primary_until"
+	primaryUntilHeader = "X-Primary-Until"
+	pinSeparator       = "."
+	pinKeyPurpose      = "pin"
+)
+
+// Read-only copy of database. Replica failed health check is skipped until it answers again.
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Serve reads of GET requests by replicas in round-robin order. Writes, transactions and reads
+// of clients pinned by read-your-writes go to primary, as well as reads while no replica is healthy.
+func WithReplicas(replicas ...*sql.DB) Option {
+	return func(d *DBExplorer) {
+		for _, db := range replicas {
+			d.replicas = append(d.replicas, &replica{db: db})
+		}
+	}
+}
+
+// Ping replicas with interval instead of default 5 seconds.
+func WithReplicaHealthCheck(interval time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.healthInterval = interval
+	}
+}
+
+// Pin client to primary for `window` after its write: response of write sets `primary_until` cookie
+// and `X-Primary-Until` header, reads carrying either of them see their own writes till deadline.
+// Deadlines are signed with `WithSigningKey`: instances behind load balancer have to share it to accept each other's pins.
+func WithReadYourWrites(window time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.pinWindow = window
+	}
+}
+
+// Derive key signing pin deadlines: client can not extend its pin to primary beyond window.
+func (d *DBExplorer) initPinKey() {
+	if d.pinWindow > 0 {
+		d.pinKey = deriveKey(d.signingKey, pinKeyPurpose)
+	}
+}
+
+// Apply pool limits to replicas and find out which of them are reachable.
+func (d *DBExplorer) initReplicas() {
+	for _, replica := range d.replicas {
+		d.pool.apply(replica.db)
+	}
+	d.checkReplicas()
+}
+
+// Ping every replica and mark it healthy if it answers.
+func (d *DBExplorer) checkReplicas() {
+	for _, replica := range d.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		replica.healthy.Store(replica.db.PingContext(ctx) == nil)
+		cancel()
+	}
+}
+
+// Check health of replicas periodically until explorer is closed.
+func (d *DBExplorer) pollReplicas() {
+	ticker := time.NewTicker(d.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stopPolling:
+			return
+		case <-ticker.C:
+			d.checkReplicas()
+		}
+	}
+}
+
+// Database to read rows of request from: next healthy replica or primary.
+func (d *DBExplorer) reader(req *Req) querier {
+	if len(d.replicas) == 0 || req.readPrimary {
+		return d.conn()
+	}
+	next := d.nextReplica.Add(1)
+	for i := range d.replicas {
+		replica := d.replicas[(next+uint64(i))%uint64(len(d.replicas))]
+		if replica.healthy.Load() {
+			return d.bind(replica.db)
+		}
+	}
+	return d.conn()
+}
+
+// Make following reads of client go to primary. Set before write is performed: reply may be lost.
+func (d *DBExplorer) pinPrimary(w http.ResponseWriter) {
+	until := strconv.FormatInt(time.Now().Add(d.pinWindow).UnixMilli(), 10)
+	until += pinSeparator + d.pinSignature(until)
+	w.Header().Set(primaryUntilHeader, until)
+	path := d.basePath // Mounted databases sign pins with their own keys: cookie of one must not replace another's.
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     primaryUntilCookie,
+		Value:    until,
+		Path:     path,
+		MaxAge:   int(d.pinWindow.Round(time.Second).Seconds()) + 1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Client is pinned to primary by deadline in header or cookie which has not passed yet.
+// Deadline not issued by explorer is ignored.
+func (d *DBExplorer) isPinned(r *http.Request) bool {
+	if d.pinWindow <= 0 {
+		return false
+	}
+	pin := r.Header.Get(primaryUntilHeader)
+	if cookie, err := r.Cookie(primaryUntilCookie); pin == "" && err == nil {
+		pin = cookie.Value
+	}
+	until, signature, signed := strings.Cut(pin, pinSeparator)
+	if !signed || !hmac.Equal([]byte(signature), []byte(d.pinSignature(until))) {
+		return false
+	}
+	deadline, err := strconv.ParseInt(until, 10, 64)
+	return err == nil && time.Now().UnixMilli() < deadline
+}
+
+func (d *DBExplorer) pinSignature(until string) string {
+	mac := hmac.New(sha256.New, d.pinKey)
+	mac.Write([]byte(until))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
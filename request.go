@@ -1,26 +1,36 @@
 package main
 
-import "net/url"
+import (
+	"context"
+	"net/url"
+)
 
 // Representation of requested table / id / params
 type Req struct {
-	table  string
-	id     int
-	params url.Values
-	body   RequestBody
+	ctx       context.Context // Context of http request with query timeout. Database operations are cancelled with it.
+	schema    *Schema         // Snapshot of schema request is served with, even if it is reloaded meanwhile.
+	table     string
+	id        []string // Components of row id: single value or several for composite primary key.
+	params    url.Values
+	body      RequestBody
+	rows      []RequestBody // Rows of bulk insert: request body is json array.
+	patch     *Patch        // Body of PATCH request. Nil for other methods.
+	principal *Principal    // Authenticated client. Nil if authentication is not configured.
+	// Rows are read from primary even if replicas are configured: client has to see its own writes.
+	readPrimary bool
+	// Conditional request headers: `If-Match` for writes, `If-None-Match` for reads.
+	ifMatch, ifNoneMatch string
 }
 
 // Representation of reply to http client.
 // Provide any reasonable result /  error.
 type Response struct {
-	status HTTPStatus  // Transient attribute for http status handling.
-	Err    string      `json:"error,omitempty"`    // Error if occurred.
-	Resp   interface{} `json:"response,omitempty"` // Any reasonable content.
-}
-
-// Wrapper for rerplying list of tables in database.
-type TablesList struct {
-	Tables []string `json:"tables"`
+	status  HTTPStatus        // Transient attribute for http status handling.
+	Err     string            `json:"error,omitempty"`    // Error if occurred.
+	Code    string            `json:"code,omitempty"`     // Machine readable code of error.
+	Fields  map[string]string `json:"fields,omitempty"`   // Reason per invalid field of submitted entity.
+	Resp    interface{}       `json:"response,omitempty"` // Any reasonable content.
+	Details map[string]string `json:"details,omitempty"`  // Machine readable details of error.
 }
 
 func (r *Req) isTableNamesQuery() bool {
@@ -28,9 +38,9 @@ func (r *Req) isTableNamesQuery() bool {
 }
 
 func (r *Req) isTableEntriesQuery() bool {
-	return len(r.table) > 1 && r.id < 0
+	return len(r.table) > 1 && len(r.id) == 0
 }
 
 func (r *Req) isByIdQuery() bool {
-	return len(r.table) > 1 && r.id > 0
+	return len(r.table) > 1 && len(r.id) > 0
 }
@@ -2,51 +2,40 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 )
 
 // To handle raw results from database.
 type RowResult struct {
-	metadata TableMetadata
+	columns  []ColumnMetadata // Selected columns in order of appearance in query.
 	entries  DBEntries
+	unmasked bool // Keep actual values of masked columns.
 }
 
-func newRowResult(metadata TableMetadata) *RowResult {
-	return &RowResult{metadata: metadata, entries: make(DBEntries, 0, 20)}
+func newRowResult(columns []ColumnMetadata) *RowResult {
+	return &RowResult{columns: columns, entries: make(DBEntries, 0, 20)}
 }
 
 // Extract raw data from single row.
 func (r *RowResult) handleSingleRowResult(row *sql.Row) (interface{}, error) {
-	colsCount := len(r.metadata.columnsInfo)
+	colsCount := len(r.columns)
 	columnVals := make([]interface{}, colsCount) // Content holder.
 	for i := 0; i < colsCount; i++ {
 		columnVals[i] = &columnVals[i]
 	}
 	err := row.Scan(columnVals...)
-	if err != nil {
-		return nil, errors.New("record not found")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFoundError(RecordNotFoungErr)
 	}
-	entry := make(DBEntry, 1)
-	for i := 0; i < colsCount; i++ {
-		switch {
-		case columnVals[i] == nil:
-			entry[r.metadata.columnNames[i]] = nil
-		default:
-			if r.metadata.columnsInfo[i].isNumericType {
-				intVal, _ := columnVals[i].(int64)
-				entry[r.metadata.columnNames[i]] = intVal
-			} else {
-				entry[r.metadata.columnNames[i]] = string(columnVals[i].([]byte))
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
-	result := map[string]DBEntry{"record": entry}
+	result := map[string]DBEntry{"record": r.toEntry(columnVals)}
 	return result, nil
 }
 
 func (r *RowResult) handleMultiRowResult(rows *sql.Rows) error {
-	colsCount := len(r.metadata.columnNames)
+	colsCount := len(r.columns)
 	columnVals := make([]interface{}, colsCount)
 
 	for rows.Next() {
@@ -57,22 +46,34 @@ func (r *RowResult) handleMultiRowResult(rows *sql.Rows) error {
 		if err != nil {
 			return err
 		}
-		entry := make(DBEntry, 1)
-		for i := 0; i < colsCount; i++ {
-			switch {
-			case columnVals[i] == nil:
-				entry[r.metadata.columnNames[i]] = nil
-			default:
-				if !r.metadata.columnsInfo[i].isNumericType {
-					entry[r.metadata.columnNames[i]] = string(columnVals[i].([]byte))
-				} else {
-					intVal, _ := columnVals[i].(int64)
-					entry[r.metadata.columnNames[i]] = intVal
-				}
-			}
+		r.entries = append(r.entries, r.toEntry(columnVals))
+	}
+	return rows.Err() // Iteration stops early on timeout or disconnect of client.
+}
+
+// Convert scanned values to entry according to types of selected columns.
+func (r *RowResult) toEntry(columnVals []interface{}) DBEntry {
+	entry := make(DBEntry, len(r.columns))
+	for i := 0; i < len(r.columns); i++ {
+		value := r.columns[i].columnType.decode(columnVals[i])
+		if r.columns[i].mask != nil && value != nil && !r.unmasked {
+			value = r.columns[i].mask(value)
+		}
+		entry[r.columns[i].fieldName] = value
+	}
+	return entry
+}
+
+// Copy of entry with values of masked columns replaced as in replies.
+func maskEntry(entry DBEntry, columns []ColumnMetadata) DBEntry {
+	masked := make(DBEntry, len(entry))
+	for column, value := range entry {
+		masked[column] = value
+	}
+	for _, column := range columns {
+		if value := masked[column.fieldName]; column.mask != nil && value != nil {
+			masked[column.fieldName] = column.mask(value)
 		}
-		r.entries = append(r.entries, entry)
 	}
-	_, err := json.Marshal(r.entries)
-	return err
+	return masked
 }
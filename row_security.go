@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	principalRef        = ":principal." // Prefix of principal attribute in row filter: `owner_id = :principal.id`
+	InvalidRowFilter    = "invalid row filter %q of table %s: expected `<column> = :principal.<attribute>`"
+	UnknownRowFilterCol = "unknown column %s in row filter of table %s"
+	RowFilterViolation  = "field %s is out of principal scope"
+)
+
+// Restriction of table rows visible to principal: `column` must be equal to principal attribute.
+// Attributes: `id`, `roles` or any claim of principal. List attributes are matched with IN.
+type RowFilter struct {
+	column    string
+	attribute string
+}
+
+// Restrict rows of table available to principal: `WithRowFilter("items", "owner_id = :principal.id")`.
+// Filters are appended as bound conditions to every select, update and delete, and enforced on insert.
+func WithRowFilter(table string, expressions ...string) Option {
+	return func(d *DBExplorer) {
+		d.rowFilterExprs[table] = append(d.rowFilterExprs[table], expressions...)
+	}
+}
+
+// Parse row filter expression against all (including hidden) columns of table.
+func parseRowFilter(table, expression string, columnsInfo []ColumnMetadata) (RowFilter, error) {
+	column, reference, found := strings.Cut(expression, "=")
+	column, reference = strings.TrimSpace(column), strings.TrimSpace(reference)
+	if !found || !strings.HasPrefix(reference, principalRef) || len(reference) == len(principalRef) {
+		return RowFilter{}, fmt.Errorf(InvalidRowFilter, expression, table)
+	}
+	if !containsColumn(columnsInfo, column) {
+		return RowFilter{}, fmt.Errorf(UnknownRowFilterCol, column, table)
+	}
+	return RowFilter{column: column, attribute: strings.TrimPrefix(reference, principalRef)}, nil
+}
+
+// Values of principal attribute. Empty if there is no principal or attribute.
+func (f RowFilter) values(principal *Principal) []interface{} {
+	if principal == nil {
+		return nil
+	}
+	var value interface{}
+	switch f.attribute {
+	case "id":
+		value = principal.ID
+	case "roles":
+		value = principal.Roles
+	default:
+		value = principal.Claims[f.attribute]
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []interface{}{v}
+	case []interface{}:
+		return v
+	case []string:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = v[i]
+		}
+		return values
+	default:
+		return []interface{}{v}
+	}
+}
+
+// Predicate restricting rows to principal scope. Nothing matches if principal lacks attribute.
+func (f RowFilter) predicate(principal *Principal) Predicate {
+	values := f.values(principal)
+	if len(values) == 0 {
+		return Predicate{sql: "1 = 0"}
+	}
+	column := "`" + f.column + "`"
+	if len(values) == 1 {
+		return Predicate{sql: column + " = ?", args: values}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+	return Predicate{sql: column + " IN (" + placeholders + ")", args: values}
+}
+
+// Row level security predicates of table for principal of request.
+func (d *DBExplorer) rowPredicates(req *Req) []Predicate {
+	filters := req.schema.rowFilters[req.table]
+	predicates := make([]Predicate, len(filters))
+	for i, filter := range filters {
+		predicates[i] = filter.predicate(req.principal)
+	}
+	return predicates
+}
+
+// Make sure written entity stays in principal scope: values outside of scope are rejected.
+// On insert (`fill`) missing scope columns are filled from principal.
+func (d *DBExplorer) enforceRowFilters(req *Req, entity DBEntry, fill bool) error {
+	if entity == nil && fill && len(req.schema.rowFilters[req.table]) > 0 {
+		return badRequestError(InvalidBodyErr)
+	}
+	for _, filter := range req.schema.rowFilters[req.table] {
+		values := filter.values(req.principal)
+		submitted, presented := entity[filter.column]
+		if !presented && !fill {
+			continue
+		}
+		if !presented && len(values) == 1 {
+			entity[filter.column] = values[0]
+			continue
+		}
+		inScope := false
+		for _, value := range values {
+			if presented && fmt.Sprint(value) == fmt.Sprint(submitted) {
+				inScope = true
+				break
+			}
+		}
+		if !inScope {
+			return forbiddenError(fmt.Sprintf(RowFilterViolation, filter.column))
+		}
+	}
+	return nil
+}
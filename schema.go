@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	schemaReloadPath = "/_schema/reload" // Endpoint to reload schema after migration.
+	schemaResource   = "_schema"         // Table of permission to reload schema: `_schema:admin`.
+)
+
+// Tables and columns requests are served with. Never modified once loaded: reload replaces it as a whole,
+// so request keeps consistent view of schema even if it is reloaded meanwhile.
+type Schema struct {
+	tables     []string                 // Exposed tables in order of database.
+	metadata   map[string]TableMetadata // Columns per table.
+	rowFilters map[string][]RowFilter   // Parsed row filters per table.
+}
+
+// Check database catalog for changes with interval and reload schema once columns differ.
+func WithSchemaPolling(interval time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.schemaInterval = interval
+	}
+}
+
+// Read tables and columns from database and replace current schema.
+// Requests in flight are finished with schema they started with.
+func (d *DBExplorer) ReloadSchema(ctx context.Context) error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	return d.reloadSchema(ctx)
+}
+
+// Reload schema found outdated by request failed with it. Requests failing meanwhile wait for the same reload
+// instead of running their own one after another, request served with already replaced schema runs none.
+func (d *DBExplorer) reloadOutdated(ctx context.Context, outdated *Schema) error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	if d.schema.Load() != outdated {
+		return nil // Reloaded since request started.
+	}
+	return d.reloadSchema(ctx)
+}
+
+// Reload schema, `reloadMu` has to be held.
+func (d *DBExplorer) reloadSchema(ctx context.Context) error {
+	version := ""
+	if d.schemaInterval > 0 {
+		var err error
+		if version, err = d.readSchemaVersion(ctx); err != nil {
+			return err
+		}
+	}
+	schema, err := d.collectMetaInfo(ctx)
+	if err != nil {
+		return err
+	}
+	d.schema.Store(schema)
+	d.schemaVersion = version
+	return nil
+}
+
+// Checksum of columns of current database. Any migration of tables changes it.
+func (d *DBExplorer) readSchemaVersion(ctx context.Context) (string, error) {
+	rows, err := d.conn().QueryContext(ctx, d.dialect.SchemaVersionQuery())
+	if err != nil {
+		return "", err
+	}
+	defer closeResources(rows)
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		for _, value := range values {
+			hash.Write([]byte(value.String))
+			hash.Write([]byte{0})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Reload schema on every change of database catalog till explorer is closed.
+func (d *DBExplorer) pollSchema() {
+	ticker := time.NewTicker(d.schemaInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stopPolling:
+			return
+		case <-ticker.C:
+			if err := d.reloadIfChanged(); err != nil {
+				log.Printf("failed to poll schema: %s", err)
+			}
+		}
+	}
+}
+
+func (d *DBExplorer) reloadIfChanged() error {
+	ctx, cancel := d.withTimeout(context.Background(), "")
+	defer cancel()
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	version, err := d.readSchemaVersion(ctx)
+	if err != nil || version == d.schemaVersion {
+		return err
+	}
+	return d.reloadSchema(ctx)
+}
+
+// Stop background polling of schema and replicas. Database handles are left open.
+func (d *DBExplorer) Close() error {
+	d.closeOnce.Do(func() { close(d.stopPolling) })
+	return nil
+}
+
+// Reload schema on demand of administrator and reply with tables of new schema.
+func (d *DBExplorer) handleSchemaReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		reply(w, Resp(nil, http.StatusMethodNotAllowed, errors.New(MethodNotAllowedErr)))
+		return
+	}
+	principal := PrincipalFromContext(r.Context())
+	if denied := d.authorize(principal, schemaResource, actionAdmin); denied != nil {
+		reply(w, Response{
+			status:  http.StatusForbidden,
+			Err:     PermissionDeniedErr,
+			Code:    CodeForbidden,
+			Details: map[string]string{"permission": denied.String()},
+		})
+		return
+	}
+	ctx, cancel := d.withTimeout(r.Context(), "")
+	defer cancel()
+	if err := d.ReloadSchema(ctx); err != nil {
+		reply(w, Resp(nil, http.StatusInternalServerError, err))
+		return
+	}
+	reply(w, Resp(map[string][]string{"tables": d.readableTables(principal)}, http.StatusOK, nil))
+}
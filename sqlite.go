@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	sqliteTablesQuery  = "SELECT name FROM sqlite_master WHERE type = 'table' AND substr(name, 1, 7) <> 'sqlite_' ORDER BY name"
+	sqliteColumnsQuery = "SELECT name, type, `notnull`, dflt_value, pk FROM pragma_table_info(?) ORDER BY cid"
+	// Columns of all indexes of table passed as the only argument. Expressions are not reported.
+	sqliteIndexedQuery = "SELECT DISTINCT i.name FROM pragma_index_list(?) AS l, pragma_index_info(l.name) AS i " +
+		"WHERE i.name IS NOT NULL"
+	// Columns having unique index of their own. Partial indexes do not make column unique.
+	sqliteUniqueQuery = "SELECT i.name FROM pragma_index_list(?) AS l, pragma_index_info(l.name) AS i " +
+		"WHERE l.`unique` AND NOT l.partial AND l.origin <> 'pk' AND (SELECT COUNT(*) FROM pragma_index_info(l.name)) = 1"
+	sqliteSchemaQuery = "SELECT type, name, sql FROM sqlite_master ORDER BY type, name"
+	sqliteCountQuery  = "SELECT COUNT(*) FROM `%s`" // SQLite keeps no statistics of table size.
+)
+
+// SQLite over `modernc.org/sqlite` driver. Foreign keys have to be enabled by connection pragma:
+// `file:data.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate`.
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Name() string { return "sqlite" }
+
+func (SQLiteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+func (SQLiteDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, sqliteTablesQuery)
+}
+
+// Columns as reported by `PRAGMA table_info`. Declared types are mapped onto column types by affinity.
+func (SQLiteDialect) Columns(ctx context.Context, q querier, table string) ([]ColumnMetadata, error) {
+	indexed, err := queryStrings(ctx, q, sqliteIndexedQuery, table)
+	if err != nil {
+		return nil, err
+	}
+	unique, err := queryStrings(ctx, q, sqliteUniqueQuery, table)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, sqliteColumnsQuery, table)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResources(rows)
+	columnsInfo := []ColumnMetadata{}
+	keyColumns, rowID := 0, -1
+	for rows.Next() {
+		var (
+			field, tType string
+			notNull, pk  int
+			tDefault     sql.NullString
+		)
+		if err := rows.Scan(&field, &tType, &notNull, &tDefault, &pk); err != nil {
+			return nil, err
+		}
+		null, key := "YES", ""
+		if notNull != 0 {
+			null = "NO"
+		}
+		switch {
+		case pk > 0:
+			key, null = "PRI", "NO"
+			keyColumns++
+			if strings.EqualFold(tType, "integer") {
+				rowID = len(columnsInfo)
+			}
+		case slices.Contains(unique, field):
+			key = "UNI"
+		case slices.Contains(indexed, field):
+			key = "MUL"
+		}
+		columnInfo := newColumnInfo(field, sqliteColumnType(tType), "", null, key, tDefault.Valid)
+		columnInfo.defaultExpr = tDefault.String
+		columnsInfo = append(columnsInfo, columnInfo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if keyColumns == 1 && rowID >= 0 {
+		// `INTEGER PRIMARY KEY` is an alias of rowid: it is assigned on insert.
+		columnsInfo[rowID].isAutoIncrement = true
+	}
+	return columnsInfo, nil
+}
+
+// Declared type understood by explorer is kept as is: `varchar(255)`, `boolean`, `datetime`...
+// Others are reduced to type of their affinity: https://www.sqlite.org/datatype3.html
+func sqliteColumnType(declared string) string {
+	if parseColumnType(declared).kind != KindString {
+		return declared
+	}
+	upper := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return "integer"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return declared
+	case strings.Contains(upper, "BLOB"), strings.TrimSpace(upper) == "":
+		return "blob"
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return "double"
+	}
+	return "numeric"
+}
+
+func (SQLiteDialect) SchemaVersionQuery() string { return sqliteSchemaQuery }
+
+// NULL is the lowest value in SQLite as in MySQL.
+func (SQLiteDialect) NullsOrder(bool) string { return "" }
+
+func (SQLiteDialect) EstimatedCountQuery(table string) (string, []interface{}) {
+	return fmt.Sprintf(sqliteCountQuery, table), nil
+}
+
+// Whole database is locked by writing transaction, rows can not be locked.
+func (SQLiteDialect) LockClause() string { return "" }
+
+// UPDATE of SQLite does not accept DEFAULT: declared expression is inlined.
+func (SQLiteDialect) DefaultValue(column ColumnMetadata) string {
+	if column.defaultExpr == "" {
+		return "NULL"
+	}
+	return "(" + column.defaultExpr + ")"
+}
+
+func (SQLiteDialect) DefaultValues() string { return " DEFAULT VALUES" }
+
+func (SQLiteDialect) Returning() bool { return true }
+
+func (SQLiteDialect) BinaryType() string { return "blob" }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+const defaultQueryTimeout = 30 * time.Second
+
+// Limit time of database operations of single request. Zero disables the limit.
+// Request exceeding it is cancelled and replied with 504.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.queryTimeout = timeout
+	}
+}
+
+// Override query timeout for requests to table: longer for reports, shorter for hot tables.
+func WithTableTimeout(table string, timeout time.Duration) Option {
+	return func(d *DBExplorer) {
+		d.tableTimeouts[table] = timeout
+	}
+}
+
+// Derive context of request to table limited by configured timeout.
+func (d *DBExplorer) withTimeout(ctx context.Context, table string) (context.Context, context.CancelFunc) {
+	timeout, configured := d.tableTimeouts[table]
+	if !configured {
+		timeout = d.queryTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Reply on failed request. Any failure after deadline of request is reported as timeout,
+// schema request was served with is reloaded if failure is caused by outdated metadata.
+func (d *DBExplorer) failure(req *Req, status HTTPStatus, err error) Response {
+	if errors.Is(req.ctx.Err(), context.DeadlineExceeded) {
+		return Resp(nil, http.StatusGatewayTimeout, timeoutError(TimeoutErr))
+	}
+	if errors.As(classifyError(err), new(schemaChangedError)) {
+		if reloadErr := d.reloadOutdated(context.WithoutCancel(req.ctx), req.schema); reloadErr != nil {
+			log.Printf("failed to reload schema: %s", reloadErr)
+		}
+	}
+	return Resp(nil, status, err)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	onConflictParam      = "on_conflict"      // Behaviour of PUT on duplicate key: `?on_conflict=update`.
+	conflictColumnsParam = "conflict_columns" // Key columns identifying existing row, primary key by default.
+	conflictUpdate       = "update"
+	upsertClause         = " ON DUPLICATE KEY UPDATE %s"
+	onConflictClause     = " ON CONFLICT (%s)"
+	doUpdateClause       = " DO UPDATE SET %s WHERE NOT (%s)"
+	doNothingClause      = " DO NOTHING"
+	// ------------------ upsert outcome --------------------------
+	upsertInserted  = "inserted"
+	upsertUpdated   = "updated"
+	upsertUnchanged = "unchanged"
+	// ------------------ errors ----------------------------------
+	InvalidConflictErr    = "invalid on_conflict %q: expected update"
+	InvalidConflictKeyErr = "conflict_columns %s do not match primary key or unique column"
+	RowFilterConflictErr  = "upsert is not permitted on table with row filters"
+	ConflictValueErr      = "field %s is required to detect conflict"
+)
+
+// Outcome of upsert of single row.
+type UpsertResult struct {
+	Key    map[string]interface{} `json:"key"`    // Id of inserted or updated row.
+	Action string                 `json:"action"` // inserted / updated / unchanged.
+}
+
+// Create rows: single entry or json array, plain insert or upsert on `on_conflict=update`.
+// Replace existing row if id is provided: `PUT /$table/$id`.
+func (d *DBExplorer) put(q querier, req *Req) (interface{}, error) {
+	tableMetadata, ok := req.schema.metadata[req.table]
+	if !ok {
+		return nil, unknownTableError(UnknownTableErr)
+	}
+	if req.isByIdQuery() {
+		return d.replace(q, req)
+	}
+	conflictColumns, err := parseConflict(req.table, req.params, tableMetadata)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case conflictColumns == nil && req.rows != nil:
+		return d.insertMany(q, req)
+	case conflictColumns == nil:
+		return d.insert(q, req)
+	case req.rows != nil:
+		if len(req.rows) == 0 {
+			return nil, badRequestError(NoRowsErr)
+		}
+		results := make([]interface{}, len(req.rows))
+		for i, row := range req.rows {
+			if row == nil {
+				return nil, badRequestError(fmt.Sprintf(InvalidRowErr, i, NotObjectErr))
+			}
+			if results[i], err = d.upsert(q, req, row, conflictColumns); err != nil {
+				return nil, wrapRowError(i, err)
+			}
+		}
+		return results, nil
+	}
+	return d.upsert(q, req, req.body, conflictColumns)
+}
+
+// Parse upsert params. Nil columns if plain insert is requested.
+func parseConflict(table string, params url.Values, tableMetadata TableMetadata) ([]string, error) {
+	mode := params.Get(onConflictParam)
+	if mode == "" {
+		return nil, nil
+	}
+	if mode != conflictUpdate {
+		return nil, badRequestError(fmt.Sprintf(InvalidConflictErr, mode))
+	}
+	raw := params.Get(conflictColumnsParam)
+	if raw == "" {
+		if len(tableMetadata.primaryKey) == 0 {
+			return nil, badRequestError(fmt.Sprintf(NoPrimaryKeyErr, table)) // Nothing to detect conflict on.
+		}
+		return tableMetadata.primaryKey, nil
+	}
+	columns := strings.Split(raw, fieldsSeparator)
+	for _, column := range columns {
+		if _, known := tableMetadata.hash[column]; !known {
+			return nil, badRequestError(fmt.Sprintf(UnknownColumnErr, column))
+		}
+	}
+	// Conflict is detected by unique constraint matching columns exactly: whole primary key or unique column.
+	sorted, primaryKey := slices.Clone(columns), slices.Clone(tableMetadata.primaryKey)
+	slices.Sort(sorted)
+	slices.Sort(primaryKey)
+	if !slices.Equal(sorted, primaryKey) && (len(columns) != 1 || !tableMetadata.hash[columns[0]].isUnique) {
+		return nil, badRequestError(fmt.Sprintf(InvalidConflictKeyErr, raw))
+	}
+	return columns, nil
+}
+
+// Insert entity or update existing row having the same key with `INSERT ... ON DUPLICATE KEY UPDATE`,
+// or `ON CONFLICT` for dialects with `RETURNING`. Submitted columns except conflict columns and primary key are updated.
+func (d *DBExplorer) upsert(q querier, req *Req, entity DBEntry, conflictColumns []string) (UpsertResult, error) {
+	if len(req.schema.rowFilters[req.table]) > 0 {
+		// Update part of statement can not be limited by WHERE: conflicting row may be out of principal scope.
+		return UpsertResult{}, forbiddenError(RowFilterConflictErr)
+	}
+	for _, column := range conflictColumns {
+		// Missing value would be filled with default and match unrelated row, NULL matches none.
+		if entity[column] == nil {
+			return UpsertResult{}, newValidationError(fmt.Sprintf(ConflictValueErr, column), column, reasonRequired)
+		}
+	}
+	tableMetadata := req.schema.metadata[req.table]
+	autoIncrement := tableMetadata.autoIncrementColumn()
+	// Generated key is ignored on plain insert, but conflict on it can only be detected if it is inserted.
+	submittedKey := entity[autoIncrement]
+	keyed := slices.Contains(conflictColumns, autoIncrement)
+	columns, values, err := d.insertValues(req, entity)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	updated := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if _, presented := entity[column]; !presented ||
+			tableMetadata.isPrimaryKeyColumn(column) || slices.Contains(conflictColumns, column) {
+			continue
+		}
+		updated = append(updated, column)
+	}
+	if keyed {
+		converted, ok := tableMetadata.getColumn(autoIncrement).columnType.convert(submittedKey)
+		if !ok {
+			message := fmt.Sprintf(InvalidIDTypeErrParrern, autoIncrement)
+			return UpsertResult{}, newValidationError(message, autoIncrement, reasonInvalidType)
+		}
+		entity[autoIncrement] = converted
+		columns = append(columns, autoIncrement)
+		values = append(values, converted)
+	}
+	sql := d.insertStatement(req.table, columns)
+	if d.dialect.Returning() {
+		return d.upsertOnConflict(q, req, entity, sql, values, conflictColumns, updated)
+	}
+
+	assignments := make([]string, 0, len(updated)+1)
+	for _, column := range updated {
+		assignments = append(assignments, fmt.Sprintf("`%s` = VALUES(`%s`)", column, column))
+	}
+	if autoIncrement != "" {
+		// Makes LastInsertId report id of updated row.
+		assignments = append(assignments, fmt.Sprintf("`%s` = LAST_INSERT_ID(`%s`)", autoIncrement, autoIncrement))
+	}
+	if len(assignments) == 0 {
+		assignments = append(assignments, fmt.Sprintf("`%s` = `%s`", conflictColumns[0], conflictColumns[0]))
+	}
+	result, err := q.ExecContext(req.ctx, sql+fmt.Sprintf(upsertClause, strings.Join(assignments, ", ")), values...)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	lastID := int64(0)
+	if autoIncrement != "" {
+		if lastID, err = result.LastInsertId(); err != nil {
+			return UpsertResult{}, err
+		}
+	}
+	// MySQL reports 1 affected row for inserted row, 2 for updated and 0 if existing row already had the same values.
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	action := upsertUnchanged
+	switch affected {
+	case 1:
+		action = upsertInserted
+	case 2:
+		action = upsertUpdated
+	}
+	return UpsertResult{Key: createdKey(tableMetadata, entity, lastID), Action: action}, nil
+}
+
+// Upsert with `INSERT ... ON CONFLICT DO UPDATE ... RETURNING`. Conflicting row is looked up beforehand
+// to tell inserted row from updated one, it is not updated if submitted values are the same.
+func (d *DBExplorer) upsertOnConflict(q querier, req *Req, entity DBEntry, insert string, values []interface{}, conflictColumns, updated []string) (UpsertResult, error) {
+	tableMetadata := req.schema.metadata[req.table]
+	keyColumns := make([]ColumnMetadata, len(tableMetadata.primaryKey))
+	for i, column := range tableMetadata.primaryKey {
+		keyColumns[i] = tableMetadata.getColumn(column)
+	}
+	selected := listColumns(keyColumns)
+	if len(keyColumns) == 0 {
+		selected = "1" // Row of table without primary key is only checked for existence.
+	}
+	byConflict := make([]Predicate, len(conflictColumns))
+	for i, column := range conflictColumns {
+		byConflict[i] = Predicate{sql: "`" + column + "` = ?", args: []interface{}{entity[column]}}
+	}
+	where, args := buildWhereClause(byConflict)
+	existing, err := scanKey(q.QueryRowContext(req.ctx, fmt.Sprintf(selectByIdQuery, selected, req.table, where), args...), keyColumns)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return UpsertResult{}, err
+	}
+
+	clause := fmt.Sprintf(onConflictClause, "`"+strings.Join(conflictColumns, "`,`")+"`")
+	if len(updated) == 0 {
+		clause += doNothingClause
+	} else {
+		assignments := make([]string, len(updated))
+		unchanged := make([]string, len(updated))
+		for i, column := range updated {
+			assignments[i] = fmt.Sprintf("`%s` = excluded.`%s`", column, column)
+			unchanged[i] = fmt.Sprintf("`%s`.`%s` IS NOT DISTINCT FROM excluded.`%s`", req.table, column, column)
+		}
+		clause += fmt.Sprintf(doUpdateClause, strings.Join(assignments, ", "), strings.Join(unchanged, " AND "))
+	}
+	statement := insert + clause + fmt.Sprintf(returningClause, selected)
+	key, err := scanKey(q.QueryRowContext(req.ctx, statement, values...), keyColumns)
+	switch {
+	case errors.Is(err, sql.ErrNoRows): // Conflicting row already has submitted values.
+		return UpsertResult{Key: existing, Action: upsertUnchanged}, nil
+	case err != nil:
+		return UpsertResult{}, err
+	case existing != nil:
+		return UpsertResult{Key: key, Action: upsertUpdated}, nil
+	}
+	return UpsertResult{Key: key, Action: upsertInserted}, nil
+}
+
+// Scan values of key columns from row. `sql.ErrNoRows` if there is no row.
+func scanKey(row *sql.Row, keyColumns []ColumnMetadata) (map[string]interface{}, error) {
+	raw := make([]interface{}, max(len(keyColumns), 1))
+	for i := range raw {
+		raw[i] = &raw[i]
+	}
+	if err := row.Scan(raw...); err != nil {
+		return nil, err
+	}
+	key := make(map[string]interface{}, len(keyColumns))
+	for i, column := range keyColumns {
+		key[column.fieldName] = column.columnType.decode(raw[i])
+	}
+	return key, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	maskedValue         = "***"
+	HiddenPrimaryKeyErr = "column %s.%s is part of primary key and can not be hidden or masked"
+	UnknownConfigColErr = "unknown column %s.%s in config"
+	MaskedColumnErr     = "column %s is masked"
+)
+
+// Replace column value in replies to client. Applied to non-NULL values only.
+type Mask func(value interface{}) interface{}
+
+// Reply constant `***` instead of column value.
+func MaskStars(interface{}) interface{} {
+	return maskedValue
+}
+
+// Reply sha256 of column value: values can be compared, but not revealed.
+func MaskHash(value interface{}) interface{} {
+	raw, _ := json.Marshal(value)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Expose only listed tables. Any other table is invisible in `GET /` and replied as unknown.
+func WithTables(tables ...string) Option {
+	return func(d *DBExplorer) {
+		if d.exposedTables == nil {
+			d.exposedTables = make(map[string]bool, len(tables))
+		}
+		for _, table := range tables {
+			d.exposedTables[table] = true
+		}
+	}
+}
+
+// Hide listed tables from `GET /` and routing.
+func WithoutTables(tables ...string) Option {
+	return func(d *DBExplorer) {
+		for _, table := range tables {
+			d.hiddenTables[table] = true
+		}
+	}
+}
+
+// Hide columns of table from reads and writes as if they do not exist.
+func WithHiddenColumns(table string, columns ...string) Option {
+	return func(d *DBExplorer) {
+		if d.hiddenColumns[table] == nil {
+			d.hiddenColumns[table] = make(map[string]bool, len(columns))
+		}
+		for _, column := range columns {
+			d.hiddenColumns[table][column] = true
+		}
+	}
+}
+
+// Replace values of columns in replies with `mask`. Masked columns are still writable,
+// but can not be used in filters and ordering to not reveal values.
+func WithMaskedColumns(table string, mask Mask, columns ...string) Option {
+	return func(d *DBExplorer) {
+		if d.maskedColumns[table] == nil {
+			d.maskedColumns[table] = make(map[string]Mask, len(columns))
+		}
+		for _, column := range columns {
+			d.maskedColumns[table][column] = mask
+		}
+	}
+}
+
+// Is table visible to clients according to allow / deny lists.
+func (d *DBExplorer) isTableExposed(table string) bool {
+	if d.hiddenTables[table] {
+		return false
+	}
+	return d.exposedTables == nil || d.exposedTables[table]
+}
+
+// Drop hidden columns and attach masks according to config.
+func (d *DBExplorer) applyColumnRules(table string, columnsInfo []ColumnMetadata) ([]ColumnMetadata, error) {
+	hidden, masked := d.hiddenColumns[table], d.maskedColumns[table]
+	known := make(map[string]bool, len(columnsInfo))
+	visible := make([]ColumnMetadata, 0, len(columnsInfo))
+	for _, colInfo := range columnsInfo {
+		known[colInfo.fieldName] = true
+		_, isMasked := masked[colInfo.fieldName]
+		if (hidden[colInfo.fieldName] || isMasked) && colInfo.isPrimaryKey {
+			return nil, fmt.Errorf(HiddenPrimaryKeyErr, table, colInfo.fieldName)
+		}
+		if hidden[colInfo.fieldName] {
+			continue
+		}
+		colInfo.mask = masked[colInfo.fieldName]
+		visible = append(visible, colInfo)
+	}
+	for column := range hidden {
+		if !known[column] {
+			return nil, fmt.Errorf(UnknownConfigColErr, table, column)
+		}
+	}
+	for column := range masked {
+		if !known[column] {
+			return nil, fmt.Errorf(UnknownConfigColErr, table, column)
+		}
+	}
+	return visible, nil
+}